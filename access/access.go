@@ -0,0 +1,69 @@
+// Package access keeps the lists of who may join the server and who runs it: the whitelist, the operators and the
+// banned players and addresses. They are stored in the same JSON files as on vanilla servers, so those files can be
+// moved between them, and every change is written back right away.
+package access
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files the lists are stored in, relative to the directory of the server
+const (
+	WhitelistFile     = "whitelist.json"
+	OpsFile           = "ops.json"
+	BannedPlayersFile = "banned-players.json"
+	BannedIPsFile     = "banned-ips.json"
+)
+
+// dateFormat is how vanilla stores dates in the lists
+const dateFormat = "2006-01-02 15:04:05 -0700"
+
+// forever is stored as the expiry date of bans that don't expire
+const forever = "forever"
+
+// readList reads a JSON list into entries. A missing file is an empty list, and is created so it can be edited.
+func readList(path string, entries interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return writeList(path, []struct{}{})
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, entries)
+}
+
+// writeList replaces the file with the entries, through a temporary file so a crash can't leave half a list behind
+func writeList(path string, entries interface{}) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return forever
+	}
+	return t.Format(dateFormat)
+}
+
+func parseDate(s string) time.Time {
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return time.Time{} // forever, or a date we can't read which vanilla treats the same
+	}
+	return t
+}
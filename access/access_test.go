@@ -0,0 +1,138 @@
+package access
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+var notch = Profile{UUID: uuid.FromStringOrNil("069a79f4-44e9-4726-a5be-fca90e38aaf5"), Name: "Notch"}
+
+func TestWhitelist(t *testing.T) {
+	path := filepath.Join(tempDir(t), WhitelistFile)
+	w, err := OpenWhitelist(path, true)
+	if err != nil {
+		t.Fatalf("OpenWhitelist() error = %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); strings.TrimSpace(string(data)) != "[]" {
+		t.Errorf("missing whitelist was created as %q, want []", data)
+	}
+	if w.Whitelisted(notch.UUID) {
+		t.Errorf("player is whitelisted before being added")
+	}
+	if added, err := w.Add(notch); !added || err != nil {
+		t.Fatalf("Add() = %v, %v", added, err)
+	}
+	if added, _ := w.Add(notch); added {
+		t.Errorf("Add() added the player twice")
+	}
+
+	reopened, err := OpenWhitelist(path, true)
+	if err != nil {
+		t.Fatalf("OpenWhitelist() error = %v", err)
+	}
+	if !reopened.Whitelisted(notch.UUID) {
+		t.Errorf("added player wasn't written to %s", path)
+	}
+	if removed, err := reopened.Remove(notch.UUID); !removed || err != nil {
+		t.Fatalf("Remove() = %v, %v", removed, err)
+	}
+	if reopened.Whitelisted(notch.UUID) {
+		t.Errorf("removed player is still whitelisted")
+	}
+	reopened.SetEnabled(false)
+	if !reopened.Whitelisted(notch.UUID) {
+		t.Errorf("everyone should be whitelisted while the whitelist is disabled")
+	}
+}
+
+func TestOps(t *testing.T) {
+	path := filepath.Join(tempDir(t), OpsFile)
+	vanilla := `[{"uuid":"069a79f4-44e9-4726-a5be-fca90e38aaf5","name":"Notch","level":4,"bypassesPlayerLimit":false}]`
+	if err := ioutil.WriteFile(path, []byte(vanilla), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := OpenOps(path)
+	if err != nil {
+		t.Fatalf("OpenOps() error = %v", err)
+	}
+	if level := ops.Level(notch.UUID); level != 4 {
+		t.Errorf("Level() = %d, want 4", level)
+	}
+	if ops.BypassesPlayerLimit(notch.UUID) {
+		t.Errorf("BypassesPlayerLimit() = true, want false like in ops.json")
+	}
+	if err := ops.Add(Op{Profile: notch, Level: 2, BypassesPlayerLimit: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if level := ops.Level(notch.UUID); level != 2 || len(ops.List()) != 1 {
+		t.Errorf("Add() of an op didn't change their level, level = %d with %d ops", level, len(ops.List()))
+	}
+	if !ops.BypassesPlayerLimit(notch.UUID) {
+		t.Errorf("BypassesPlayerLimit() = false after Add(), want true")
+	}
+	if removed, err := ops.Remove(notch.UUID); !removed || err != nil {
+		t.Fatalf("Remove() = %v, %v", removed, err)
+	}
+	if level := ops.Level(notch.UUID); level != 0 {
+		t.Errorf("Level() of removed op = %d, want 0", level)
+	}
+}
+
+func TestBans(t *testing.T) {
+	dir := tempDir(t)
+	playersPath, ipsPath := filepath.Join(dir, BannedPlayersFile), filepath.Join(dir, BannedIPsFile)
+	vanilla := `[
+  {"uuid":"069a79f4-44e9-4726-a5be-fca90e38aaf5","name":"Notch","created":"2020-08-11 20:00:00 +0000","source":"Server","expires":"forever","reason":"Griefing"},
+  {"uuid":"853c80ef-3c37-49fd-aa49-938b674adae6","name":"jeb_","created":"2020-08-11 20:00:00 +0000","source":"Server","expires":"2020-08-12 20:00:00 +0000","reason":"Expired"}
+]`
+	if err := ioutil.WriteFile(playersPath, []byte(vanilla), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bans, err := OpenBans(playersPath, ipsPath)
+	if err != nil {
+		t.Fatalf("OpenBans() error = %v", err)
+	}
+	if ban, ok := bans.PlayerBan(notch.UUID); !ok || ban.Reason != "Griefing" || !ban.Expires.IsZero() {
+		t.Errorf("PlayerBan() = %+v, %v, want a permanent ban for Griefing", ban, ok)
+	}
+	if _, ok := bans.PlayerBan(uuid.FromStringOrNil("853c80ef-3c37-49fd-aa49-938b674adae6")); ok {
+		t.Errorf("expired ban is still enforced")
+	}
+
+	ip := net.ParseIP("192.168.0.10")
+	if err := bans.BanIP(ip, NewBan("", "", time.Now().Add(time.Hour))); err != nil {
+		t.Fatalf("BanIP() error = %v", err)
+	}
+	reopened, err := OpenBans(playersPath, ipsPath)
+	if err != nil {
+		t.Fatalf("OpenBans() error = %v", err)
+	}
+	ban, ok := reopened.IPBan(net.ParseIP("192.168.0.10"))
+	if !ok || ban.Reason != DefaultBanReason || ban.Expires.IsZero() {
+		t.Errorf("IPBan() = %+v, %v, want a temporary ban with the default reason", ban, ok)
+	}
+	if pardoned, err := reopened.PardonIP(ip); !pardoned || err != nil {
+		t.Fatalf("PardonIP() = %v, %v", pardoned, err)
+	}
+	if pardoned, err := reopened.PardonPlayer(notch.UUID); !pardoned || err != nil {
+		t.Fatalf("PardonPlayer() = %v, %v", pardoned, err)
+	}
+	if len(reopened.Players()) != 0 || len(reopened.IPs()) != 0 {
+		t.Errorf("bans left after pardoning everyone: %v %v", reopened.Players(), reopened.IPs())
+	}
+}
@@ -0,0 +1,267 @@
+package access
+
+import (
+	mcnet "github.com/masp/mcgo/net"
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultBanReason and DefaultBanSource are used for bans that don't say why or by whom, like vanilla
+const (
+	DefaultBanReason = "Banned by an operator."
+	DefaultBanSource = "Server"
+)
+
+// BanEntry is when, by whom and why something was banned
+type BanEntry struct {
+	Created time.Time
+	Source  string
+	Expires time.Time // zero if the ban doesn't expire
+	Reason  string
+}
+
+// NewBan returns a ban made now, filling in the default reason and source if they are empty
+func NewBan(source string, reason string, expires time.Time) BanEntry {
+	if source == "" {
+		source = DefaultBanSource
+	}
+	if reason == "" {
+		reason = DefaultBanReason
+	}
+	return BanEntry{Created: time.Now(), Source: source, Expires: expires, Reason: reason}
+}
+
+func (e BanEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// BannedPlayer is a player on the ban list
+type BannedPlayer struct {
+	Profile
+	BanEntry
+}
+
+// BannedIP is an address on the ban list
+type BannedIP struct {
+	IP net.IP
+	BanEntry
+}
+
+// banJSON is how both kinds of bans are stored
+type banJSON struct {
+	UUID    string `json:"uuid,omitempty"`
+	Name    string `json:"name,omitempty"`
+	IP      string `json:"ip,omitempty"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+func (e BanEntry) toJSON() banJSON {
+	return banJSON{Created: formatDate(e.Created), Source: e.Source, Expires: formatDate(e.Expires), Reason: e.Reason}
+}
+
+func (j banJSON) entry() BanEntry {
+	return BanEntry{Created: parseDate(j.Created), Source: j.Source, Expires: parseDate(j.Expires), Reason: j.Reason}
+}
+
+// Bans are the banned players and addresses, which are stored in banned-players.json and banned-ips.json. Expired bans
+// are ignored, and removed the next time the list is written.
+type Bans struct {
+	playersPath string
+	ipsPath     string
+
+	mu      sync.RWMutex
+	players []BannedPlayer
+	ips     []BannedIP
+}
+
+var _ mcnet.BanList = (*Bans)(nil)
+
+// OpenBans reads the ban lists at the paths, which are created if they don't exist
+func OpenBans(playersPath string, ipsPath string) (*Bans, error) {
+	b := &Bans{playersPath: playersPath, ipsPath: ipsPath}
+	return b, b.Reload()
+}
+
+// Reload reads the ban lists from their files again, for changes made while the server is running
+func (b *Bans) Reload() error {
+	var players, ips []banJSON
+	if err := readList(b.playersPath, &players); err != nil {
+		return err
+	}
+	if err := readList(b.ipsPath, &ips); err != nil {
+		return err
+	}
+
+	bannedPlayers := make([]BannedPlayer, 0, len(players))
+	for _, j := range players {
+		id, err := uuid.FromString(j.UUID)
+		if err != nil {
+			continue // vanilla skips entries it can't read too
+		}
+		bannedPlayers = append(bannedPlayers, BannedPlayer{Profile{id, j.Name}, j.entry()})
+	}
+	bannedIPs := make([]BannedIP, 0, len(ips))
+	for _, j := range ips {
+		ip := net.ParseIP(j.IP)
+		if ip == nil {
+			continue
+		}
+		bannedIPs = append(bannedIPs, BannedIP{ip, j.entry()})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.players = bannedPlayers
+	b.ips = bannedIPs
+	return nil
+}
+
+// PlayerBan returns the ban of a player, if they are banned
+func (b *Bans) PlayerBan(id uuid.UUID) (mcnet.Ban, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if i := b.playerIndex(id); i >= 0 {
+		return mcnet.Ban{Reason: b.players[i].Reason, Expires: b.players[i].Expires}, true
+	}
+	return mcnet.Ban{}, false
+}
+
+// IPBan returns the ban of an address, if it is banned
+func (b *Bans) IPBan(ip net.IP) (mcnet.Ban, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if i := b.ipIndex(ip); i >= 0 {
+		return mcnet.Ban{Reason: b.ips[i].Reason, Expires: b.ips[i].Expires}, true
+	}
+	return mcnet.Ban{}, false
+}
+
+// BanPlayer bans a player, replacing the ban they already had
+func (b *Bans) BanPlayer(p Profile, entry BanEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i := b.playerIndex(p.UUID); i >= 0 {
+		b.players[i] = BannedPlayer{p, entry}
+	} else {
+		b.players = append(b.players, BannedPlayer{p, entry})
+	}
+	return b.savePlayers()
+}
+
+// PardonPlayer lifts the ban of a player. It returns false if they weren't banned.
+func (b *Bans) PardonPlayer(id uuid.UUID) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.playerIndex(id)
+	if i < 0 {
+		return false, nil
+	}
+	b.players = append(b.players[:i:i], b.players[i+1:]...)
+	return true, b.savePlayers()
+}
+
+// BanIP bans an address, replacing the ban it already had
+func (b *Bans) BanIP(ip net.IP, entry BanEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i := b.ipIndex(ip); i >= 0 {
+		b.ips[i] = BannedIP{ip, entry}
+	} else {
+		b.ips = append(b.ips, BannedIP{ip, entry})
+	}
+	return b.saveIPs()
+}
+
+// PardonIP lifts the ban of an address. It returns false if it wasn't banned.
+func (b *Bans) PardonIP(ip net.IP) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.ipIndex(ip)
+	if i < 0 {
+		return false, nil
+	}
+	b.ips = append(b.ips[:i:i], b.ips[i+1:]...)
+	return true, b.saveIPs()
+}
+
+// Players returns the players that are banned
+func (b *Bans) Players() []BannedPlayer {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	now := time.Now()
+	var players []BannedPlayer
+	for _, p := range b.players {
+		if !p.expired(now) {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// IPs returns the addresses that are banned
+func (b *Bans) IPs() []BannedIP {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	now := time.Now()
+	var ips []BannedIP
+	for _, ip := range b.ips {
+		if !ip.expired(now) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+func (b *Bans) playerIndex(id uuid.UUID) int {
+	now := time.Now()
+	for i, p := range b.players {
+		if uuid.Equal(p.UUID, id) && !p.expired(now) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *Bans) ipIndex(ip net.IP) int {
+	now := time.Now()
+	for i, banned := range b.ips {
+		if banned.IP.Equal(ip) && !banned.expired(now) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *Bans) savePlayers() error {
+	now := time.Now()
+	list := make([]banJSON, 0, len(b.players))
+	for _, p := range b.players {
+		if p.expired(now) {
+			continue
+		}
+		j := p.toJSON()
+		j.UUID = p.UUID.String()
+		j.Name = p.Name
+		list = append(list, j)
+	}
+	return writeList(b.playersPath, list)
+}
+
+func (b *Bans) saveIPs() error {
+	now := time.Now()
+	list := make([]banJSON, 0, len(b.ips))
+	for _, ip := range b.ips {
+		if ip.expired(now) {
+			continue
+		}
+		j := ip.toJSON()
+		j.IP = ip.IP.String()
+		list = append(list, j)
+	}
+	return writeList(b.ipsPath, list)
+}
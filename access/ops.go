@@ -0,0 +1,103 @@
+package access
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"sync"
+)
+
+// DefaultOpLevel is the level players get when they are made operator, which allows every command
+const DefaultOpLevel = 4
+
+// Op is an operator of the server. The level decides which commands they can run: 1 lets them bypass spawn
+// protection, 2 allows cheats, 3 commands to manage players and 4 everything.
+type Op struct {
+	Profile
+	Level               int  `json:"level"`
+	BypassesPlayerLimit bool `json:"bypassesPlayerLimit"`
+}
+
+// Ops is the list of operators, which is stored in ops.json
+type Ops struct {
+	path string
+
+	mu  sync.RWMutex
+	ops []Op
+}
+
+// OpenOps reads the operators at path, which is created if it doesn't exist
+func OpenOps(path string) (*Ops, error) {
+	o := &Ops{path: path}
+	return o, o.Reload()
+}
+
+// Reload reads the operators from their file again, for changes made while the server is running
+func (o *Ops) Reload() error {
+	var ops []Op
+	if err := readList(o.path, &ops); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ops = ops
+	return nil
+}
+
+// Level returns the operator level of a player, 0 if they aren't an operator
+func (o *Ops) Level(id uuid.UUID) int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if i := o.index(id); i >= 0 {
+		return o.ops[i].Level
+	}
+	return 0
+}
+
+// BypassesPlayerLimit returns true if the player is an operator that may join even when the server is full
+func (o *Ops) BypassesPlayerLimit(id uuid.UUID) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if i := o.index(id); i >= 0 {
+		return o.ops[i].BypassesPlayerLimit
+	}
+	return false
+}
+
+// Add makes a player operator, or changes their level if they already are one
+func (o *Ops) Add(op Op) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if i := o.index(op.UUID); i >= 0 {
+		o.ops[i] = op
+	} else {
+		o.ops = append(o.ops, op)
+	}
+	return writeList(o.path, o.ops)
+}
+
+// Remove stops a player from being operator. It returns false if they weren't one.
+func (o *Ops) Remove(id uuid.UUID) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := o.index(id)
+	if i < 0 {
+		return false, nil
+	}
+	o.ops = append(o.ops[:i:i], o.ops[i+1:]...)
+	return true, writeList(o.path, o.ops)
+}
+
+// List returns every operator
+func (o *Ops) List() []Op {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return append([]Op(nil), o.ops...)
+}
+
+func (o *Ops) index(id uuid.UUID) int {
+	for i, op := range o.ops {
+		if uuid.Equal(op.UUID, id) {
+			return i
+		}
+	}
+	return -1
+}
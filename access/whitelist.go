@@ -0,0 +1,106 @@
+package access
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"sync"
+)
+
+// Profile is a player on one of the lists, with the name they had when they were added
+type Profile struct {
+	UUID uuid.UUID `json:"uuid"`
+	Name string    `json:"name"`
+}
+
+// Whitelist is the list of players that may join while it's enabled. It's stored in whitelist.json.
+type Whitelist struct {
+	path string
+
+	mu      sync.RWMutex
+	enabled bool
+	players []Profile
+}
+
+// OpenWhitelist reads the whitelist at path, which is created if it doesn't exist
+func OpenWhitelist(path string, enabled bool) (*Whitelist, error) {
+	w := &Whitelist{path: path, enabled: enabled}
+	return w, w.Reload()
+}
+
+// Reload reads the whitelist from its file again, for changes made while the server is running
+func (w *Whitelist) Reload() error {
+	var players []Profile
+	if err := readList(w.path, &players); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.players = players
+	return nil
+}
+
+func (w *Whitelist) Enabled() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.enabled
+}
+
+// SetEnabled turns the whitelist on or off until the server restarts, white-list in server.properties decides if it's
+// on after that
+func (w *Whitelist) SetEnabled(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.enabled = enabled
+}
+
+// Whitelisted returns true if the player may join, which everyone may while the whitelist is disabled
+func (w *Whitelist) Whitelisted(id uuid.UUID) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return !w.enabled || w.index(id) >= 0
+}
+
+// Contains returns true if the player is on the whitelist
+func (w *Whitelist) Contains(id uuid.UUID) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.index(id) >= 0
+}
+
+// Add puts the player on the whitelist. It returns false if they already were.
+func (w *Whitelist) Add(p Profile) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.index(p.UUID) >= 0 {
+		return false, nil
+	}
+	w.players = append(w.players, p)
+	return true, writeList(w.path, w.players)
+}
+
+// Remove takes the player off the whitelist. It returns false if they weren't on it.
+func (w *Whitelist) Remove(id uuid.UUID) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	i := w.index(id)
+	if i < 0 {
+		return false, nil
+	}
+	w.players = append(w.players[:i:i], w.players[i+1:]...)
+	return true, writeList(w.path, w.players)
+}
+
+// Players returns everyone on the whitelist
+func (w *Whitelist) Players() []Profile {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]Profile(nil), w.players...)
+}
+
+func (w *Whitelist) index(id uuid.UUID) int {
+	for i, p := range w.players {
+		if uuid.Equal(p.UUID, id) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,124 @@
+// Package anvil reads and writes worlds in the Anvil format used by vanilla saves, where chunks are stored in region
+// files of 32x32 chunks at <world>/region/r.<x>.<z>.mca
+package anvil
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Tnze/go-mc/save/region"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	ErrChunkNotFound = errors.New("chunk not found in region")
+)
+
+const regionSize = 32 // chunks
+
+// Store gives access to the region files of a single dimension in a save. Region files are opened the first time
+// a chunk in them is accessed and kept open until the store is closed. It's safe to use from several goroutines.
+type Store struct {
+	Dir string
+
+	mu      sync.Mutex                    // guards regions and the region files
+	regions map[pstn.Chunk]*region.Region // keyed by region position
+}
+
+// Open returns a store for the dimension saved in dir, which is the directory that contains the region directory
+// (the world directory for the overworld, or DIM-1/DIM1 for the nether and end).
+func Open(dir string) *Store {
+	return &Store{
+		Dir:     dir,
+		regions: make(map[pstn.Chunk]*region.Region),
+	}
+}
+
+func regionPos(pos pstn.Chunk) pstn.Chunk {
+	return pstn.Chunk{X: pos.X >> 5, Z: pos.Z >> 5}
+}
+
+// sectorIndex returns the arguments for region.ReadSector and region.WriteSector. go-mc indexes the region header
+// as [z][x] (in the order it's stored on disk), so the local z coordinate comes first.
+func sectorIndex(pos pstn.Chunk) (int, int) {
+	return int(pos.Z & (regionSize - 1)), int(pos.X & (regionSize - 1))
+}
+
+func (s *Store) regionPath(pos pstn.Chunk) string {
+	return filepath.Join(s.Dir, "region", fmt.Sprintf("r.%d.%d.mca", pos.X, pos.Z))
+}
+
+// region returns the open region file that contains the chunk, or nil if it doesn't exist and create is false.
+// s.mu must be held.
+func (s *Store) region(pos pstn.Chunk, create bool) (*region.Region, error) {
+	rpos := regionPos(pos)
+	if r, ok := s.regions[rpos]; ok {
+		return r, nil
+	}
+
+	path := s.regionPath(rpos)
+	r, err := region.Open(path)
+	if os.IsNotExist(err) {
+		if !create {
+			return nil, nil
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		r, err = region.Create(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening region %s: %w", path, err)
+	}
+	s.regions[rpos] = r
+	return r, nil
+}
+
+// LoadChunk reads the chunk at pos from its region file. If the chunk was never saved, ErrChunkNotFound is returned.
+func (s *Store) LoadChunk(pos pstn.Chunk) (*chunks.ChunkColumn, error) {
+	data, err := s.readSector(pos)
+	if err != nil {
+		return nil, err
+	}
+	column, err := decodeColumn(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding chunk %v: %w", pos, err)
+	}
+	return column, nil
+}
+
+func (s *Store) readSector(pos pstn.Chunk) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.region(pos, false)
+	if err != nil {
+		return nil, err
+	}
+	x, z := sectorIndex(pos)
+	if r == nil || !r.ExistSector(x, z) {
+		return nil, ErrChunkNotFound
+	}
+
+	data, err := r.ReadSector(x, z)
+	if err != nil {
+		return nil, fmt.Errorf("reading chunk %v: %w", pos, err)
+	}
+	return data, nil
+}
+
+// Close closes all region files that were opened by the store
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var firstErr error
+	for pos, r := range s.regions {
+		if err := r.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.regions, pos)
+	}
+	return firstErr
+}
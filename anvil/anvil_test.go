@@ -0,0 +1,403 @@
+package anvil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"github.com/Tnze/go-mc/nbt"
+	"github.com/Tnze/go-mc/save/region"
+	"github.com/masp/mcgo/biome"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeRawChunk stores a chunk compound in the region file the same way vanilla does
+func writeRawChunk(t *testing.T, dir string, pos pstn.Chunk, c map[string]interface{}) {
+	path := filepath.Join(dir, "region", "r.-1.0.mca")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r, err := region.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var b bytes.Buffer
+	b.WriteByte(compressionZlib)
+	zw := zlib.NewWriter(&b)
+	if err := proto.WriteCompound(zw, "", c); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+
+	x, z := sectorIndex(pos)
+	if err := r.WriteSector(x, z, b.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_LoadChunk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anvil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Section 4 is stone at the bottom layer and oak stairs facing east above it, the rest is air
+	blockStates := make([]chunks.BlockState, chunks.BlocksInSection)
+	for i := 0; i < 256; i++ {
+		blockStates[i] = 1
+	}
+	blockStates[1<<8] = 2
+	biomes := make([]int32, chunks.BiomesInChunk)
+	for i := range biomes {
+		biomes[i] = biome.PlainsID
+	}
+	biomes[0] = 2000 // not in the registry
+	heights := make([]chunks.BlockState, chunks.Width*chunks.Depth)
+	for i := range heights {
+		heights[i] = 66
+	}
+	motionBlocking := chunks.NewPaddedBlockArray(9, heights).Data
+
+	pos := pstn.Chunk{X: -3, Z: 5}
+	writeRawChunk(t, dir, pos, map[string]interface{}{
+		"DataVersion": int32(2578),
+		"Level": map[string]interface{}{
+			"xPos": pos.X,
+			"zPos": pos.Z,
+			"Sections": []interface{}{
+				map[string]interface{}{"Y": int8(-1), "SkyLight": bytes.Repeat([]byte{0xff}, 2048)},
+				map[string]interface{}{
+					"Y": int8(4),
+					"Palette": []interface{}{
+						map[string]interface{}{"Name": "minecraft:air"},
+						map[string]interface{}{"Name": "minecraft:stone"},
+						map[string]interface{}{
+							"Name":       "minecraft:oak_stairs",
+							"Properties": map[string]string{"facing": "east", "half": "bottom"},
+						},
+					},
+					"BlockStates": chunks.NewPaddedBlockArray(4, blockStates).Data,
+				},
+			},
+			"Biomes":     biomes,
+			"Heightmaps": map[string]interface{}{"MOTION_BLOCKING": motionBlocking, "WORLD_SURFACE": []int64{1}},
+			"TileEntities": []interface{}{
+				map[string]interface{}{"id": "minecraft:sign", "x": int32(-45), "y": int32(65), "z": int32(81), "Text1": `{"text":"hi"}`},
+			},
+		},
+	})
+
+	store := Open(dir)
+	defer store.Close()
+	column, err := store.LoadChunk(pos)
+	if err != nil {
+		t.Fatalf("LoadChunk(%v) error = %v", pos, err)
+	}
+
+	if got := column.BlockAt(7, 64, 7); got != blocks.Stone {
+		t.Errorf("BlockAt(7, 64, 7) = %d, want stone (%d)", got, blocks.Stone)
+	}
+	stairs := blocks.MustGet("minecraft:oak_stairs", map[string]string{"facing": "east"})
+	if got := column.BlockAt(0, 65, 0); got != stairs {
+		t.Errorf("BlockAt(0, 65, 0) = %d, want east stairs (%d)", got, stairs)
+	}
+	if got := column.BlockAt(1, 65, 0); got != blocks.Air {
+		t.Errorf("BlockAt(1, 65, 0) = %d, want air", got)
+	}
+	if got := column.BiomeAt(0, 0, 0); got != biome.PlainsID {
+		t.Errorf("BiomeAt(0, 0, 0) = %d, want plains since 2000 isn't registered", got)
+	}
+	if column.VoidSection.SkyLight.Data[0] != 0xff {
+		t.Errorf("void section sky light wasn't loaded")
+	}
+	if got := column.HeightMap().MotionBlocking; !reflect.DeepEqual(got, motionBlocking) {
+		t.Errorf("HeightMap() = %v, want the saved MOTION_BLOCKING heightmap", got)
+	}
+	if _, ok := column.Heightmaps()["WORLD_SURFACE"]; ok {
+		t.Errorf("loaded a WORLD_SURFACE heightmap of the wrong length")
+	}
+	sign := column.BlockEntityAt(3, 65, 1)
+	if sign == nil || sign.ID != "minecraft:sign" || sign.Data["Text1"] != `{"text":"hi"}` {
+		t.Errorf("BlockEntityAt(3, 65, 1) = %v, want sign with text hi", sign)
+	}
+
+	if _, err := store.LoadChunk(pstn.Chunk{X: -3, Z: 6}); err != ErrChunkNotFound {
+		t.Errorf("LoadChunk() of missing chunk error = %v, want ErrChunkNotFound", err)
+	}
+	if _, err := store.LoadChunk(pstn.Chunk{X: 100, Z: 100}); err != ErrChunkNotFound {
+		t.Errorf("LoadChunk() in missing region error = %v, want ErrChunkNotFound", err)
+	}
+}
+
+func TestStore_SaveChunk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anvil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pos := pstn.Chunk{X: 40, Z: -7}
+	column := chunks.NewChunk(pos)
+	stairs := blocks.MustGet("minecraft:oak_stairs", map[string]string{"half": "top", "shape": "inner_left"})
+	column.SetBlockAt(0, 0, 0, blocks.Bedrock)
+	column.SetBlockAt(15, 255, 15, stairs)
+	for x := 0; x < chunks.Width; x++ {
+		column.SetBlockAt(x, 100, 3, chunks.BlockState(x+1)) // enough block types for a 5 bit palette
+	}
+	column.SetBiomeAt(8, 8, 8, 2000)
+	column.SetBlockEntity(1, 2, 3, chunks.NewSign("saved"))
+
+	store := Open(dir)
+	if err := store.SaveChunk(column); err != nil {
+		t.Fatalf("SaveChunk() error = %v", err)
+	}
+	if column.Dirty() {
+		t.Errorf("got Dirty() = true after saving, want false")
+	}
+	store.Close()
+
+	store = Open(dir)
+	defer store.Close()
+	loaded, err := store.LoadChunk(pos)
+	if err != nil {
+		t.Fatalf("LoadChunk() error = %v", err)
+	}
+	for x := 0; x < chunks.Width; x++ {
+		for z := 0; z < chunks.Depth; z++ {
+			for y := 0; y < chunks.Height; y++ {
+				if got, want := loaded.BlockAt(x, y, z), column.BlockAt(x, y, z); got != want {
+					t.Fatalf("BlockAt(%d, %d, %d) = %d, want %d", x, y, z, got, want)
+				}
+			}
+		}
+	}
+	if got := loaded.BlockEntityAt(1, 2, 3); got == nil || got.Data["Text1"] != `{"text":"saved"}` {
+		t.Errorf("BlockEntityAt(1, 2, 3) = %v, want sign", got)
+	}
+	if got := loaded.BiomeAt(8, 8, 8); got != biome.PlainsID {
+		t.Errorf("BiomeAt(8, 8, 8) = %d, want plains", got)
+	}
+}
+
+func TestStore_UnknownBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anvil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Section 2 has stone at the bottom and a layer of unknown blocks above it, section 3 only unknown blocks
+	air := map[string]interface{}{"Name": "minecraft:air"}
+	stone := map[string]interface{}{"Name": "minecraft:stone"}
+	crystal := map[string]interface{}{"Name": "othermod:crystal", "Properties": map[string]string{"glowing": "true"}}
+	blockStates := make([]chunks.BlockState, chunks.BlocksInSection)
+	for i := 0; i < 256; i++ {
+		blockStates[i] = 1
+		blockStates[1<<8|i] = 2
+	}
+	onlyUnknown := make([]chunks.BlockState, chunks.BlocksInSection)
+	onlyUnknown[0] = 1
+	pos := pstn.Chunk{X: -1, Z: 0}
+	writeRawChunk(t, dir, pos, map[string]interface{}{
+		"DataVersion": int32(2578),
+		"Level": map[string]interface{}{
+			"xPos": pos.X,
+			"zPos": pos.Z,
+			"Sections": []interface{}{
+				map[string]interface{}{
+					"Y":           int8(2),
+					"Palette":     []interface{}{air, stone, crystal},
+					"BlockStates": chunks.NewPaddedBlockArray(4, blockStates).Data,
+				},
+				map[string]interface{}{
+					"Y":           int8(3),
+					"Palette":     []interface{}{air, crystal},
+					"BlockStates": chunks.NewPaddedBlockArray(4, onlyUnknown).Data,
+				},
+			},
+		},
+	})
+
+	store := Open(dir)
+	defer store.Close()
+	column, err := store.LoadChunk(pos)
+	if err != nil {
+		t.Fatalf("LoadChunk() error = %v", err)
+	}
+	if got := column.BlockAt(3, 33, 4); got != blocks.Air {
+		t.Errorf("BlockAt(3, 33, 4) = %d, want air for the unknown block", got)
+	}
+	column.SetBlockAt(0, 33, 0, blocks.Stone)
+	if err := store.SaveChunk(column); err != nil {
+		t.Fatalf("SaveChunk() error = %v", err)
+	}
+
+	loaded, err := store.LoadChunk(pos)
+	if err != nil {
+		t.Fatalf("LoadChunk() of the saved chunk error = %v", err)
+	}
+	want := &chunks.UnknownBlock{Name: "othermod:crystal", Properties: map[string]string{"glowing": "true"}}
+	for _, p := range []pstn.Block{{X: 3, Y: 33, Z: 4}, {X: 15, Y: 33, Z: 15}, {X: 0, Y: 48, Z: 0}} {
+		if got := loaded.UnknownBlockAt(int(p.X), int(p.Y), int(p.Z)); !reflect.DeepEqual(got, want) {
+			t.Errorf("UnknownBlockAt(%v) = %v, want the block as it was loaded", p, got)
+		}
+	}
+	if got := loaded.BlockAt(0, 33, 0); got != blocks.Stone || loaded.UnknownBlockAt(0, 33, 0) != nil {
+		t.Errorf("BlockAt(0, 33, 0) = %d, want the stone that replaced the unknown block", got)
+	}
+	if got := loaded.BlockAt(0, 32, 0); got != blocks.Stone {
+		t.Errorf("BlockAt(0, 32, 0) = %d, want stone", got)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anvil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := Level{
+		Name:      "test",
+		Spawn:     pstn.Block{X: 10, Y: 70, Z: -20},
+		Seed:      -1234567890123,
+		Time:      42,
+		GameType:  2,
+		GameRules: map[string]string{"keepInventory": "true", "randomTickSpeed": "3"},
+		Dimension: "minecraft:the_nether",
+		Generator: "terrain",
+	}
+	for i := 0; i < 2; i++ { // the second write moves the first to level.dat_old
+		if err := WriteLevel(dir, want); err != nil {
+			t.Fatalf("WriteLevel() error = %v", err)
+		}
+	}
+	got, err := ReadLevel(dir)
+	if err != nil {
+		t.Fatalf("ReadLevel() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadLevel() = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "level.dat_old")); err != nil {
+		t.Errorf("level.dat_old wasn't kept: %v", err)
+	}
+
+	flat := map[string]interface{}{"type": "minecraft:flat"}
+	want.VanillaGenerator = flat
+	if err := WriteLevel(dir, want); err != nil {
+		t.Fatalf("WriteLevel() error = %v", err)
+	}
+	var raw struct {
+		Data struct {
+			WorldGenSettings struct {
+				Dimensions map[string]struct {
+					Type      string                 `nbt:"type"`
+					Generator map[string]interface{} `nbt:"generator"`
+				} `nbt:"dimensions"`
+			}
+		}
+	}
+	readRawLevel(t, dir, &raw)
+	dims := raw.Data.WorldGenSettings.Dimensions
+	for _, typ := range []string{"minecraft:overworld", "minecraft:the_nether", "minecraft:the_end"} {
+		if dims[typ].Type != typ {
+			t.Errorf("dimensions[%s].type = %q, want %q", typ, dims[typ].Type, typ)
+		}
+	}
+	if got := dims["minecraft:the_nether"].Generator["type"]; got != "minecraft:flat" {
+		t.Errorf("nether generator type = %v, want the flat generator of the level", got)
+	}
+	if got := dims["minecraft:overworld"].Generator["type"]; got != "minecraft:noise" {
+		t.Errorf("overworld generator type = %v, want minecraft:noise", got)
+	}
+}
+
+// readRawLevel decodes the level.dat in dir into v
+func readRawLevel(t *testing.T, dir string, v interface{}) {
+	f, err := os.Open(levelPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nbt.NewDecoder(r).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayerData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anvil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id := uuid.FromStringOrNil("069a79f4-44e9-4726-a5be-fca90e38aaf5")
+	if _, err := ReadPlayerData(dir, id); !os.IsNotExist(err) {
+		t.Errorf("ReadPlayerData() of a new player error = %v, want not exist", err)
+	}
+	want := PlayerData{
+		Pos:              pstn.Entity{X: 10.5, Y: 64, Z: -3.25},
+		Yaw:              90,
+		Pitch:            -15.5,
+		OnGround:         true,
+		Dimension:        "minecraft:the_nether",
+		World:            "world_nether",
+		GameType:         1,
+		SelectedItemSlot: 4,
+		Inventory: []PlayerItem{
+			{Slot: HotbarSlot, ID: "minecraft:stone", Count: 64},
+			{Slot: OffhandSlot, ID: "minecraft:diamond_sword", Count: 1, Tag: map[string]interface{}{"Damage": int32(3)}},
+		},
+	}
+	if err := WritePlayerData(dir, id, want); err != nil {
+		t.Fatalf("WritePlayerData() error = %v", err)
+	}
+	got, err := ReadPlayerData(dir, id)
+	if err != nil {
+		t.Fatalf("ReadPlayerData() error = %v", err)
+	}
+	raw := got.Raw
+	got.Raw = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadPlayerData() = %+v, want %+v", got, want)
+	}
+
+	// everything the server doesn't own is written back as it was read
+	raw["Health"] = float32(7.5)
+	raw["XpLevel"] = int32(12)
+	raw["abilities"] = map[string]interface{}{"mayfly": byte(1)}
+	raw["EnderItems"] = []interface{}{map[string]interface{}{"Slot": byte(0), "id": "minecraft:ender_pearl", "Count": byte(3)}}
+	raw["Attributes"] = []interface{}{map[string]interface{}{"Name": "minecraft:generic.max_health", "Base": 20.0}}
+	want.Raw = raw
+	want.Pos.Y = 70
+	if err := WritePlayerData(dir, id, want); err != nil {
+		t.Fatalf("WritePlayerData() error = %v", err)
+	}
+	if got, err = ReadPlayerData(dir, id); err != nil {
+		t.Fatalf("ReadPlayerData() error = %v", err)
+	}
+	if got.Pos.Y != 70 {
+		t.Errorf("ReadPlayerData().Pos.Y = %v, want 70", got.Pos.Y)
+	}
+	for _, key := range []string{"Health", "XpLevel", "abilities", "EnderItems", "Attributes"} {
+		if !reflect.DeepEqual(got.Raw[key], raw[key]) {
+			t.Errorf("ReadPlayerData().Raw[%q] = %#v, want %#v", key, got.Raw[key], raw[key])
+		}
+	}
+}
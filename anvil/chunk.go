@@ -0,0 +1,200 @@
+package anvil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"github.com/Tnze/go-mc/nbt"
+	"github.com/masp/mcgo/biome"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	log "github.com/sirupsen/logrus"
+	"io"
+)
+
+// Compression schemes of a chunk in a region file
+const (
+	compressionGzip = 1
+	compressionZlib = 2
+	compressionNone = 3
+)
+
+// minDataVersion is the first data version (20w17a) that stores block states padded to whole longs, like 1.16 does
+const minDataVersion = 2529
+
+type columnNBT struct {
+	DataVersion int32
+	Level       struct {
+		PosX         int32 `nbt:"xPos"`
+		PosZ         int32 `nbt:"zPos"`
+		Sections     []sectionNBT
+		Biomes       []int32
+		TileEntities []map[string]interface{}
+		Heightmaps   map[string][]int64
+	}
+}
+
+type sectionNBT struct {
+	Y           int8
+	Palette     []paletteEntry
+	BlockStates []int64
+	BlockLight  []byte
+	SkyLight    []byte
+}
+
+type paletteEntry struct {
+	Name       string
+	Properties map[string]string
+}
+
+func decompress(data []byte) (io.Reader, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty chunk data")
+	}
+	r := bytes.NewReader(data[1:])
+	switch data[0] {
+	case compressionGzip:
+		return gzip.NewReader(r)
+	case compressionZlib:
+		return zlib.NewReader(r)
+	case compressionNone:
+		return r, nil
+	default:
+		return nil, fmt.Errorf("unknown compression scheme %d", data[0])
+	}
+}
+
+func decodeColumn(data []byte) (*chunks.ChunkColumn, error) {
+	r, err := decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	var c columnNBT
+	if err := nbt.NewDecoder(r).Decode(&c); err != nil {
+		return nil, err
+	}
+	if c.DataVersion < minDataVersion {
+		return nil, fmt.Errorf("data version %d is older than 1.16 and not supported", c.DataVersion)
+	}
+
+	column := chunks.NewChunk(pstn.Chunk{X: c.Level.PosX, Z: c.Level.PosZ})
+	for _, sec := range c.Level.Sections {
+		if err := loadSection(column, sec); err != nil {
+			return nil, fmt.Errorf("section %d: %w", sec.Y, err)
+		}
+	}
+	loadBiomes(column, c.Level.Biomes)
+	for _, data := range c.Level.TileEntities {
+		loadBlockEntity(column, data)
+	}
+	if c.Level.Heightmaps != nil {
+		column.SetHeightmaps(c.Level.Heightmaps)
+	}
+	column.ClearDirty()
+	return column, nil
+}
+
+func loadLight(light *chunks.LightingArray, data []byte) {
+	if len(data) == len(light.Data) {
+		copy(light.Data[:], data)
+	}
+}
+
+func loadSection(column *chunks.ChunkColumn, sec sectionNBT) error {
+	var lighting *chunks.ChunkLighting
+	switch {
+	case sec.Y == -1:
+		lighting = &column.VoidSection
+	case sec.Y == chunks.SectionsInChunk:
+		lighting = &column.SkySection
+	case sec.Y >= 0 && sec.Y < chunks.SectionsInChunk:
+		lighting = &column.Sections[sec.Y].Lighting
+	default:
+		return nil
+	}
+	loadLight(&lighting.SkyLight, sec.SkyLight)
+	loadLight(&lighting.BlockLight, sec.BlockLight)
+
+	if len(sec.Palette) == 0 || sec.Y < 0 || sec.Y >= chunks.SectionsInChunk {
+		return nil
+	}
+
+	// Blocks that aren't in the registry are kept as they were saved, so they aren't lost when the chunk is saved again
+	palette := make([]chunks.BlockState, len(sec.Palette))
+	unknown := make([]*chunks.UnknownBlock, len(sec.Palette))
+	for i, entry := range sec.Palette {
+		state, err := blocks.Get(entry.Name, entry.Properties)
+		if err != nil {
+			log.Warnf("anvil: keeping unknown block as air in chunk %v: %v", column.Pos, err)
+			unknown[i] = &chunks.UnknownBlock{Name: entry.Name, Properties: entry.Properties}
+		}
+		palette[i] = state
+	}
+
+	bits := bitsForPalette(len(palette))
+	indices := chunks.UnpackPaddedBlockArray(bits, sec.BlockStates, chunks.BlocksInSection)
+	baseY := int(sec.Y) * chunks.SectionHeight
+	for i, index := range indices {
+		if int(index) >= len(palette) {
+			return fmt.Errorf("block state index %d out of palette of size %d", index, len(palette))
+		}
+		x, y, z := i&0xf, i>>8, (i>>4)&0xf
+		if unknown[index] != nil {
+			column.SetUnknownBlock(x, baseY+y, z, unknown[index])
+		} else {
+			column.SetBlockAt(x, baseY+y, z, palette[index])
+		}
+	}
+	return nil
+}
+
+// bitsForPalette is the number of bits used for each block in BlockStates, which is enough to index the palette but
+// never less than 4
+func bitsForPalette(size int) int {
+	bits := 4
+	for 1<<bits < size {
+		bits++
+	}
+	return bits
+}
+
+func loadBiomes(column *chunks.ChunkColumn, ids []int32) {
+	if len(ids) != chunks.BiomesInChunk {
+		return // keep the default biome
+	}
+	const cells = chunks.Width / chunks.BiomeCellSize
+	for i, id := range ids {
+		if !biome.Registered(id) {
+			// The client rejects chunks with biomes it wasn't sent in the registry
+			id = biome.PlainsID
+		}
+		x, z, y := i%cells, (i/cells)%cells, i/(cells*cells)
+		column.SetBiomeAt(x*chunks.BiomeCellSize, y*chunks.BiomeCellSize, z*chunks.BiomeCellSize, id)
+	}
+}
+
+func loadBlockEntity(column *chunks.ChunkColumn, data map[string]interface{}) {
+	id, _ := data["id"].(string)
+	x, okX := data["x"].(int32)
+	y, okY := data["y"].(int32)
+	z, okZ := data["z"].(int32)
+	if id == "" || !okX || !okY || !okZ {
+		log.Warnf("anvil: skipping block entity without id or position in chunk %v", column.Pos)
+		return
+	}
+
+	be := &chunks.BlockEntity{ID: id, Data: make(map[string]interface{}, len(data))}
+	for k, v := range data {
+		switch k {
+		case "id", "x", "y", "z", "keepPacked":
+		default:
+			be.Data[k] = v
+		}
+	}
+	pos := pstn.Block{X: x, Y: y, Z: z}
+	lx, ly, lz := pstn.BlockInChunk(pos)
+	column.SetBlockEntity(lx, ly, lz, be)
+}
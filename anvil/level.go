@@ -0,0 +1,181 @@
+package anvil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/Tnze/go-mc/nbt"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Level is the world-wide data stored in level.dat
+type Level struct {
+	Name      string
+	Spawn     pstn.Block
+	Seed      int64
+	Time      int64             // world age in ticks
+	GameType  int32             // gamemode of new players: 0 survival, 1 creative, 2 adventure, 3 spectator
+	GameRules map[string]string // values of /gamerule, vanilla stores them all as strings
+	// VanillaGenerator is the generator compound of Dimension in WorldGenSettings, which vanilla generates the chunks
+	// that weren't saved with. It isn't read back. The other dimensions, and Dimension if it's nil, get the default
+	// generator of vanilla.
+	VanillaGenerator map[string]interface{}
+
+	// Vanilla doesn't know these, they are kept so the world is generated the same way when it's loaded again
+	Dimension string // name of the dimension type
+	Generator string
+}
+
+type levelNBT struct {
+	Data struct {
+		LevelName              string
+		SpawnX, SpawnY, SpawnZ int32
+		RandomSeed             int64
+		WorldGenSettings       struct {
+			Seed int64 `nbt:"seed"`
+		}
+		Time          int64
+		GameType      int32
+		GameRules     map[string]string
+		McgoDimension string `nbt:"mcgo:dimension"`
+		McgoGenerator string `nbt:"mcgo:generator"`
+	}
+}
+
+// dimensions returns the dimensions of WorldGenSettings, which vanilla needs all of to load the settings
+func dimensions(l Level) map[string]interface{} {
+	biomeSource := func(typ string) map[string]interface{} {
+		return map[string]interface{}{"type": typ, "seed": l.Seed}
+	}
+	noise := func(settings string, biomes map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"type":         "minecraft:noise",
+			"seed":         l.Seed,
+			"settings":     settings,
+			"biome_source": biomes,
+		}
+	}
+
+	overworldBiomes := biomeSource("minecraft:vanilla_layered")
+	overworldBiomes["large_biomes"] = false
+	netherBiomes := biomeSource("minecraft:multi_noise")
+	netherBiomes["preset"] = "minecraft:nether"
+	generators := map[string]map[string]interface{}{
+		"minecraft:overworld":  noise("minecraft:overworld", overworldBiomes),
+		"minecraft:the_nether": noise("minecraft:nether", netherBiomes),
+		"minecraft:the_end":    noise("minecraft:end", biomeSource("minecraft:the_end")),
+	}
+	if _, ok := generators[l.Dimension]; ok && l.VanillaGenerator != nil {
+		generators[l.Dimension] = l.VanillaGenerator
+	}
+
+	dims := make(map[string]interface{}, len(generators))
+	for typ, generator := range generators {
+		dims[typ] = map[string]interface{}{"type": typ, "generator": generator}
+	}
+	return dims
+}
+
+func levelPath(worldDir string) string {
+	return filepath.Join(worldDir, "level.dat")
+}
+
+// ReadLevel reads level.dat from the world directory. If the world has no level.dat, the error satisfies
+// os.IsNotExist.
+func ReadLevel(worldDir string) (Level, error) {
+	f, err := os.Open(levelPath(worldDir))
+	if err != nil {
+		return Level{}, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return Level{}, err
+	}
+
+	var l levelNBT
+	if err := nbt.NewDecoder(r).Decode(&l); err != nil {
+		return Level{}, err
+	}
+	seed := l.Data.WorldGenSettings.Seed
+	if seed == 0 {
+		seed = l.Data.RandomSeed // before 1.16
+	}
+	return Level{
+		Name:      l.Data.LevelName,
+		Spawn:     pstn.Block{X: l.Data.SpawnX, Y: l.Data.SpawnY, Z: l.Data.SpawnZ},
+		Seed:      seed,
+		Time:      l.Data.Time,
+		GameType:  l.Data.GameType,
+		GameRules: l.Data.GameRules,
+		Dimension: l.Data.McgoDimension,
+		Generator: l.Data.McgoGenerator,
+	}, nil
+}
+
+// WriteLevel writes level.dat to the world directory. The previous level.dat is kept as level.dat_old like vanilla
+// does, so a crash while saving doesn't lose the world.
+func WriteLevel(worldDir string, l Level) error {
+	data := map[string]interface{}{
+		"DataVersion": int32(DataVersion),
+		"version":     int32(19133), // NBT format version of anvil
+		"Version": map[string]interface{}{
+			"Id":       int32(DataVersion),
+			"Name":     "1.16.2",
+			"Snapshot": false,
+		},
+		"LevelName":     l.Name,
+		"SpawnX":        l.Spawn.X,
+		"SpawnY":        l.Spawn.Y,
+		"SpawnZ":        l.Spawn.Z,
+		"RandomSeed":    l.Seed,
+		"Time":          l.Time,
+		"DayTime":       l.Time,
+		"LastPlayed":    time.Now().UnixNano() / int64(time.Millisecond),
+		"GameType":      l.GameType,
+		"Difficulty":    uint8(2),
+		"hardcore":      false,
+		"initialized":   true,
+		"allowCommands": true,
+		"WorldGenSettings": map[string]interface{}{
+			"seed":              l.Seed,
+			"generate_features": true,
+			"bonus_chest":       false,
+			"dimensions":        dimensions(l),
+		},
+		"mcgo:dimension": l.Dimension,
+		"mcgo:generator": l.Generator,
+	}
+	if len(l.GameRules) > 0 {
+		rules := make(map[string]interface{}, len(l.GameRules))
+		for name, value := range l.GameRules {
+			rules[name] = value
+		}
+		data["GameRules"] = rules
+	}
+
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if err := proto.WriteCompound(zw, "", map[string]interface{}{"Data": data}); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(worldDir, 0755); err != nil {
+		return err
+	}
+	path := levelPath(worldDir)
+	if err := ioutil.WriteFile(path+"_new", b.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(path, path+"_old"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(path+"_new", path)
+}
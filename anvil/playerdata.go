@@ -0,0 +1,177 @@
+package anvil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/Tnze/go-mc/nbt"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Slots of items in player data, which are numbered differently than in the inventory window
+const (
+	HotbarSlot  = 0    // first of 9
+	MainSlot    = 9    // first of 27
+	ArmorSlot   = 100  // first of 4, from feet to head
+	OffhandSlot = -106 // vanilla's 150 stored as a signed byte
+)
+
+// PlayerItem is a stack of items in the inventory of a player
+type PlayerItem struct {
+	Slot  int8
+	ID    string // name of the item, e.g. minecraft:stone
+	Count int8
+	Tag   map[string]interface{} // extra data like enchantments, nil if there is none
+}
+
+// PlayerData is what is kept about a player between sessions, stored in playerdata/<uuid>.dat of the main world
+type PlayerData struct {
+	Pos      pstn.Entity
+	Yaw      float32
+	Pitch    float32
+	OnGround bool
+	// Dimension is the type of the dimension the player is in, e.g. minecraft:overworld. Vanilla has one world per
+	// dimension type, so World is kept too to tell worlds of the same type apart.
+	Dimension        string
+	World            string
+	GameType         int32
+	SelectedItemSlot int32 // selected hotbar slot, 0 to 8
+	Inventory        []PlayerItem
+	// Raw is the whole compound the data was read from, nil for players that weren't saved before. WritePlayerData
+	// writes the fields above into a copy of it, so everything else vanilla stores (health, experience, the ender
+	// chest...) is kept.
+	Raw map[string]interface{}
+}
+
+type playerDataNBT struct {
+	Pos              []float64
+	Rotation         []float32
+	OnGround         byte
+	Dimension        string
+	McgoWorld        string `nbt:"mcgo:world"`
+	PlayerGameType   int32  `nbt:"playerGameType"`
+	SelectedItemSlot int32
+	Inventory        []struct {
+		Slot  int8
+		ID    string `nbt:"id"`
+		Count int8
+		Tag   map[string]interface{} `nbt:"tag"`
+	}
+}
+
+func playerDataPath(worldDir string, id uuid.UUID) string {
+	return filepath.Join(worldDir, "playerdata", id.String()+".dat")
+}
+
+// ReadPlayerData reads the data of a player from the world directory. If the player wasn't saved before, the error
+// satisfies os.IsNotExist.
+func ReadPlayerData(worldDir string, id uuid.UUID) (PlayerData, error) {
+	f, err := os.Open(playerDataPath(worldDir, id))
+	if err != nil {
+		return PlayerData{}, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return PlayerData{}, err
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return PlayerData{}, err
+	}
+
+	var (
+		p   playerDataNBT
+		raw map[string]interface{}
+	)
+	if err := nbt.Unmarshal(data, &p); err != nil {
+		return PlayerData{}, err
+	}
+	if err := nbt.Unmarshal(data, &raw); err != nil {
+		return PlayerData{}, err
+	}
+	d := PlayerData{
+		Raw:              raw,
+		OnGround:         p.OnGround != 0,
+		Dimension:        p.Dimension,
+		World:            p.McgoWorld,
+		GameType:         p.PlayerGameType,
+		SelectedItemSlot: p.SelectedItemSlot,
+	}
+	if len(p.Pos) == 3 {
+		d.Pos = pstn.Entity{X: p.Pos[0], Y: p.Pos[1], Z: p.Pos[2]}
+	}
+	if len(p.Rotation) == 2 {
+		d.Yaw, d.Pitch = p.Rotation[0], p.Rotation[1]
+	}
+	for _, it := range p.Inventory {
+		d.Inventory = append(d.Inventory, PlayerItem{Slot: it.Slot, ID: it.ID, Count: it.Count, Tag: it.Tag})
+	}
+	return d, nil
+}
+
+// WritePlayerData writes the data of a player to the world directory, on top of the compound it was read from. The
+// previous file is kept with the suffix _old like vanilla does.
+func WritePlayerData(worldDir string, id uuid.UUID, d PlayerData) error {
+	inventory := make([]interface{}, 0, len(d.Inventory))
+	for _, it := range d.Inventory {
+		item := map[string]interface{}{
+			"Slot":  it.Slot,
+			"id":    it.ID,
+			"Count": it.Count,
+		}
+		if it.Tag != nil {
+			item["tag"] = it.Tag
+		}
+		inventory = append(inventory, item)
+	}
+	data := make(map[string]interface{}, len(d.Raw)+10)
+	for k, v := range d.Raw {
+		data[k] = v
+	}
+	data["DataVersion"] = int32(DataVersion)
+	data["UUID"] = uuidInts(id)
+	data["Pos"] = []interface{}{d.Pos.X, d.Pos.Y, d.Pos.Z}
+	data["Rotation"] = []interface{}{d.Yaw, d.Pitch}
+	data["OnGround"] = d.OnGround
+	data["Dimension"] = d.Dimension
+	data["mcgo:world"] = d.World
+	data["playerGameType"] = d.GameType
+	data["SelectedItemSlot"] = d.SelectedItemSlot
+	data["Inventory"] = inventory
+
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if err := proto.WriteCompound(zw, "", data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	path := playerDataPath(worldDir, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+"_new", b.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(path, path+"_old"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(path+"_new", path)
+}
+
+// uuidInts returns the UUID as four ints, which is how vanilla stores UUIDs since 1.16
+func uuidInts(id uuid.UUID) []int32 {
+	ints := make([]int32, 4)
+	for i := range ints {
+		b := id[i*4 : i*4+4]
+		ints[i] = int32(uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]))
+	}
+	return ints
+}
@@ -0,0 +1,154 @@
+package anvil
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	"time"
+)
+
+// DataVersion is the data version of 1.16.2, which is what saved chunks and level.dat are marked with
+const DataVersion = 2578
+
+// SaveChunk writes the column to its region file, creating the region file if needed, and marks it as saved.
+// The column can keep changing while it's saved, the changes are saved the next time.
+func (s *Store) SaveChunk(column *chunks.ChunkColumn) error {
+	return column.Save(func(snapshot *chunks.ChunkColumn) error {
+		var b bytes.Buffer
+		b.WriteByte(compressionZlib)
+		zw := zlib.NewWriter(&b)
+		c, err := encodeColumn(snapshot)
+		if err == nil {
+			err = proto.WriteCompound(zw, "", c)
+		}
+		if err != nil {
+			return fmt.Errorf("encoding chunk %v: %w", snapshot.Pos, err)
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		return s.writeSector(snapshot.Pos, b.Bytes())
+	})
+}
+
+func (s *Store) writeSector(pos pstn.Chunk, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.region(pos, true)
+	if err != nil {
+		return err
+	}
+	x, z := sectorIndex(pos)
+	if err := r.WriteSector(x, z, data); err != nil {
+		return fmt.Errorf("writing chunk %v: %w", pos, err)
+	}
+	return nil
+}
+
+func encodeColumn(column *chunks.ChunkColumn) (map[string]interface{}, error) {
+	var sections []interface{}
+	for y := range column.Sections {
+		if sec := &column.Sections[y]; !sec.IsAir() || sec.HasUnknownBlocks() {
+			encoded, err := encodeSection(int8(y), sec)
+			if err != nil {
+				return nil, fmt.Errorf("section %d: %w", y, err)
+			}
+			sections = append(sections, encoded)
+		}
+	}
+
+	biomes := make([]int32, chunks.BiomesInChunk)
+	const cells = chunks.Width / chunks.BiomeCellSize
+	for i := range biomes {
+		x, z, y := i%cells, (i/cells)%cells, i/(cells*cells)
+		biomes[i] = column.BiomeAt(x*chunks.BiomeCellSize, y*chunks.BiomeCellSize, z*chunks.BiomeCellSize)
+	}
+
+	var blockEntities []interface{}
+	for _, be := range column.BlockEntities() {
+		blockEntities = append(blockEntities, be.NBT())
+	}
+
+	level := map[string]interface{}{
+		"xPos":          column.Pos.X,
+		"zPos":          column.Pos.Z,
+		"Status":        "full",
+		"LastUpdate":    time.Now().Unix(),
+		"InhabitedTime": int64(0),
+		// Light isn't calculated properly yet, so let vanilla relight the chunk when it's loaded
+		"isLightOn":    false,
+		"Sections":     sections,
+		"Biomes":       biomes,
+		"TileEntities": blockEntities,
+		"Entities":     []interface{}{},
+	}
+	// Heightmaps are only kept while no block changed, vanilla computes the ones that are missing
+	if loaded := column.Heightmaps(); len(loaded) > 0 {
+		heightmaps := make(map[string]interface{}, len(loaded))
+		for name, data := range loaded {
+			heightmaps[name] = data
+		}
+		level["Heightmaps"] = heightmaps
+	}
+	return map[string]interface{}{
+		"DataVersion": int32(DataVersion),
+		"Level":       level,
+	}, nil
+}
+
+func encodeSection(y int8, sec *chunks.ChunkSection) (map[string]interface{}, error) {
+	var palette []interface{}
+	// keyed by the state, or by the *chunks.UnknownBlock for blocks that were loaded without being known
+	paletteIndex := make(map[interface{}]chunks.BlockState)
+	states := make([]chunks.BlockState, chunks.BlocksInSection)
+	for i := range states {
+		x, secY, z := i&0xf, i>>8, (i>>4)&0xf
+		var key interface{} = sec.BlockAt(x, secY, z)
+		if unknown := sec.UnknownBlockAt(x, secY, z); unknown != nil {
+			key = unknown
+		}
+		index, ok := paletteIndex[key]
+		if !ok {
+			entry, err := paletteEntryFor(key)
+			if err != nil {
+				return nil, err
+			}
+			index = chunks.BlockState(len(palette))
+			paletteIndex[key] = index
+			palette = append(palette, entry)
+		}
+		states[i] = index
+	}
+
+	return map[string]interface{}{
+		"Y":           y,
+		"Palette":     palette,
+		"BlockStates": chunks.NewPaddedBlockArray(bitsForPalette(len(palette)), states).Data,
+	}, nil
+}
+
+// paletteEntryFor returns the palette entry of a block state, or of an unknown block the way it was loaded
+func paletteEntryFor(block interface{}) (map[string]interface{}, error) {
+	var (
+		name  string
+		props map[string]string
+	)
+	switch b := block.(type) {
+	case *chunks.UnknownBlock:
+		name, props = b.Name, b.Properties
+	case chunks.BlockState:
+		var ok bool
+		if name, props, ok = blocks.Lookup(b); !ok {
+			return nil, fmt.Errorf("block state %d isn't in the registry", b)
+		}
+	}
+	entry := map[string]interface{}{"Name": name}
+	if len(props) > 0 {
+		entry["Properties"] = props
+	}
+	return entry, nil
+}
@@ -83,10 +83,49 @@ func OverworldDimension() DimensionDef {
 	}
 }
 
-func plainBiome() BiomeDef {
+func NetherDimension() DimensionDef {
+	return DimensionDef{
+		PiglinSafe:      1,
+		Natural:         0,
+		AmbientLight:    0.1,
+		FixedTime:       18000,
+		Infiniburn:      "minecraft:infiniburn_nether",
+		RespawnAnchor:   1,
+		HasSkylight:     0,
+		BedWorks:        0,
+		Effects:         "minecraft:the_nether",
+		HasRaids:        0,
+		LogicalHeight:   128,
+		CoordinateScale: 8.0,
+		Ultrawarm:       1,
+		HasCeiling:      1,
+	}
+}
+
+func EndDimension() DimensionDef {
+	return DimensionDef{
+		PiglinSafe:      0,
+		Natural:         0,
+		AmbientLight:    0,
+		FixedTime:       6000,
+		Infiniburn:      "minecraft:infiniburn_end",
+		RespawnAnchor:   0,
+		HasSkylight:     0,
+		BedWorks:        0,
+		Effects:         "minecraft:the_end",
+		HasRaids:        1,
+		LogicalHeight:   256,
+		CoordinateScale: 1.0,
+		Ultrawarm:       0,
+		HasCeiling:      0,
+	}
+}
+
+// overworldBiome fills in the effects every overworld biome shares
+func overworldBiome(precipitation string, category string, skyColor int32) BiomeDef {
 	b := BiomeDef{}
-	b.Precipitation = "rain"
-	b.Effects.SkyColor = 7907327
+	b.Precipitation = precipitation
+	b.Effects.SkyColor = skyColor
 	b.Effects.WaterFogColor = 329011
 	b.Effects.FogColor = 12638463
 	b.Effects.WaterColor = 4159204
@@ -94,17 +133,119 @@ func plainBiome() BiomeDef {
 	b.Effects.MoodSound.Offset = 2.0
 	b.Effects.MoodSound.Sound = "minecraft:ambient.cave"
 	b.Effects.MoodSound.BlockSearchExtent = 8
+	b.Category = category
+	return b
+}
+
+func plainBiome() BiomeDef {
+	b := overworldBiome("rain", "plains", 7907327)
 	b.Depth = 0.125
 	b.Temp = 0.8
 	b.Scale = 0.05
 	b.Downfall = 0.4
-	b.Category = "plains"
 	return b
 }
 
+func oceanBiome() BiomeDef {
+	b := overworldBiome("rain", "ocean", 8103167)
+	b.Depth = -1.0
+	b.Temp = 0.5
+	b.Scale = 0.1
+	b.Downfall = 0.5
+	return b
+}
+
+func desertBiome() BiomeDef {
+	b := overworldBiome("none", "desert", 7254527)
+	b.Depth = 0.125
+	b.Temp = 2.0
+	b.Scale = 0.05
+	b.Downfall = 0
+	return b
+}
+
+func forestBiome() BiomeDef {
+	b := overworldBiome("rain", "forest", 7972607)
+	b.Depth = 0.1
+	b.Temp = 0.7
+	b.Scale = 0.2
+	b.Downfall = 0.8
+	return b
+}
+
+func snowyTundraBiome() BiomeDef {
+	b := overworldBiome("snow", "icy", 8364543)
+	b.Depth = 0.125
+	b.Temp = 0
+	b.Scale = 0.05
+	b.Downfall = 0.5
+	return b
+}
+
+func beachBiome() BiomeDef {
+	b := overworldBiome("rain", "beach", 7907327)
+	b.Depth = 0
+	b.Temp = 0.8
+	b.Scale = 0.025
+	b.Downfall = 0.4
+	return b
+}
+
+func netherWastesBiome() BiomeDef {
+	b := BiomeDef{}
+	b.Precipitation = "none"
+	b.Effects.SkyColor = 7254527
+	b.Effects.WaterFogColor = 329011
+	b.Effects.FogColor = 3344392
+	b.Effects.WaterColor = 4159204
+	b.Effects.MoodSound.TickDelay = 6000
+	b.Effects.MoodSound.Offset = 2.0
+	b.Effects.MoodSound.Sound = "minecraft:ambient.nether_wastes.mood"
+	b.Effects.MoodSound.BlockSearchExtent = 8
+	b.Depth = 0.1
+	b.Temp = 2.0
+	b.Scale = 0.2
+	b.Downfall = 0
+	b.Category = "nether"
+	return b
+}
+
+func theEndBiome() BiomeDef {
+	b := BiomeDef{}
+	b.Precipitation = "none"
+	b.Effects.SkyColor = 0
+	b.Effects.WaterFogColor = 329011
+	b.Effects.FogColor = 10518688
+	b.Effects.WaterColor = 4159204
+	b.Effects.MoodSound.TickDelay = 6000
+	b.Effects.MoodSound.Offset = 2.0
+	b.Effects.MoodSound.Sound = "minecraft:ambient.cave"
+	b.Effects.MoodSound.BlockSearchExtent = 8
+	b.Depth = 0.1
+	b.Temp = 0.5
+	b.Scale = 0.2
+	b.Downfall = 0.5
+	b.Category = "the_end"
+	return b
+}
+
+// Dimension type IDs
 const (
 	OverworldID = 0
-	PlainsID    = 0
+	NetherID    = 1
+	EndID       = 2
+)
+
+// Biome IDs match the vanilla ones so chunks from saves can be sent as they are
+const (
+	OceanID        = 0
+	PlainsID       = 1
+	DesertID       = 2
+	ForestID       = 4
+	NetherWastesID = 8
+	TheEndID       = 9
+	SnowyTundraID  = 12
+	BeachID        = 16
 )
 
 func BuildRegistry() DimensionBiomeRegistry {
@@ -117,17 +258,60 @@ func BuildRegistry() DimensionBiomeRegistry {
 					ID:      OverworldID,
 					Element: OverworldDimension(),
 				},
+				{
+					Name:    "minecraft:the_nether",
+					ID:      NetherID,
+					Element: NetherDimension(),
+				},
+				{
+					Name:    "minecraft:the_end",
+					ID:      EndID,
+					Element: EndDimension(),
+				},
 			},
 		},
 		Biomes: BiomeRoot{
 			TypeName: "minecraft:worldgen/biome",
 			Biomes: []BiomeEntry{
-				{
-					Name:    "minecraft:plains",
-					ID:      PlainsID,
-					Element: plainBiome(),
-				},
+				{Name: "minecraft:ocean", ID: OceanID, Element: oceanBiome()},
+				{Name: "minecraft:plains", ID: PlainsID, Element: plainBiome()},
+				{Name: "minecraft:desert", ID: DesertID, Element: desertBiome()},
+				{Name: "minecraft:forest", ID: ForestID, Element: forestBiome()},
+				{Name: "minecraft:nether_wastes", ID: NetherWastesID, Element: netherWastesBiome()},
+				{Name: "minecraft:the_end", ID: TheEndID, Element: theEndBiome()},
+				{Name: "minecraft:snowy_tundra", ID: SnowyTundraID, Element: snowyTundraBiome()},
+				{Name: "minecraft:beach", ID: BeachID, Element: beachBiome()},
 			},
 		},
 	}
 }
+
+// Registered returns true if the biome is in the registry sent to clients
+func Registered(id int32) bool {
+	for _, b := range BuildRegistry().Biomes.Biomes {
+		if b.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// IDByName returns the ID of a registered biome, e.g. minecraft:plains
+func IDByName(name string) (int32, bool) {
+	for _, b := range BuildRegistry().Biomes.Biomes {
+		if b.Name == name {
+			return b.ID, true
+		}
+	}
+	return 0, false
+}
+
+// NameByID returns the name of a registered biome, e.g. minecraft:plains
+func NameByID(id int32) (string, bool) {
+	for _, b := range BuildRegistry().Biomes.Biomes {
+		if b.ID == id {
+			return b.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,103 @@
+// Code generated by cmd/datagen from ../reports/blocks.json; DO NOT EDIT.
+
+package blocks
+
+// Default states of every block
+const (
+	Air              State = 0
+	Stone            State = 1
+	Granite          State = 2
+	PolishedGranite  State = 3
+	Diorite          State = 4
+	PolishedDiorite  State = 5
+	Andesite         State = 6
+	PolishedAndesite State = 7
+	GrassBlock       State = 9
+	Dirt             State = 10
+	CoarseDirt       State = 11
+	Podzol           State = 13
+	Cobblestone      State = 14
+	OakPlanks        State = 15
+	SprucePlanks     State = 16
+	BirchPlanks      State = 17
+	JunglePlanks     State = 18
+	AcaciaPlanks     State = 19
+	DarkOakPlanks    State = 20
+	OakSapling       State = 21
+	Bedrock          State = 33
+	Water            State = 34
+	Lava             State = 50
+	Sand             State = 66
+	RedSand          State = 67
+	Gravel           State = 68
+	GoldOre          State = 69
+	IronOre          State = 70
+	CoalOre          State = 71
+	OakLog           State = 74
+	OakLeaves        State = 158
+	Glass            State = 231
+	Sandstone        State = 246
+	Cobweb           State = 1341
+	ShortGrass       State = 1342
+	Obsidian         State = 1434
+	Torch            State = 1435
+	OakStairs        State = 1965
+	Chest            State = 2035
+	OakSign          State = 3383
+	OakWallSign      State = 3737
+	Snow             State = 3921
+	Ice              State = 3929
+	SnowBlock        State = 3930
+	Netherrack       State = 3999
+	EndStone         State = 5158
+)
+
+// registry is sorted by the first state ID of each block
+var registry = []Block{
+	{Name: "minecraft:air", MinState: 0, DefaultState: 0},
+	{Name: "minecraft:stone", MinState: 1, DefaultState: 1},
+	{Name: "minecraft:granite", MinState: 2, DefaultState: 2},
+	{Name: "minecraft:polished_granite", MinState: 3, DefaultState: 3},
+	{Name: "minecraft:diorite", MinState: 4, DefaultState: 4},
+	{Name: "minecraft:polished_diorite", MinState: 5, DefaultState: 5},
+	{Name: "minecraft:andesite", MinState: 6, DefaultState: 6},
+	{Name: "minecraft:polished_andesite", MinState: 7, DefaultState: 7},
+	{Name: "minecraft:grass_block", MinState: 8, DefaultState: 9, Properties: []Property{{Name: "snowy", Values: []string{"true", "false"}}}},
+	{Name: "minecraft:dirt", MinState: 10, DefaultState: 10},
+	{Name: "minecraft:coarse_dirt", MinState: 11, DefaultState: 11},
+	{Name: "minecraft:podzol", MinState: 12, DefaultState: 13, Properties: []Property{{Name: "snowy", Values: []string{"true", "false"}}}},
+	{Name: "minecraft:cobblestone", MinState: 14, DefaultState: 14},
+	{Name: "minecraft:oak_planks", MinState: 15, DefaultState: 15},
+	{Name: "minecraft:spruce_planks", MinState: 16, DefaultState: 16},
+	{Name: "minecraft:birch_planks", MinState: 17, DefaultState: 17},
+	{Name: "minecraft:jungle_planks", MinState: 18, DefaultState: 18},
+	{Name: "minecraft:acacia_planks", MinState: 19, DefaultState: 19},
+	{Name: "minecraft:dark_oak_planks", MinState: 20, DefaultState: 20},
+	{Name: "minecraft:oak_sapling", MinState: 21, DefaultState: 21, Properties: []Property{{Name: "stage", Values: []string{"0", "1"}}}},
+	{Name: "minecraft:bedrock", MinState: 33, DefaultState: 33},
+	{Name: "minecraft:water", MinState: 34, DefaultState: 34, Properties: []Property{{Name: "level", Values: []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15"}}}},
+	{Name: "minecraft:lava", MinState: 50, DefaultState: 50, Properties: []Property{{Name: "level", Values: []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15"}}}},
+	{Name: "minecraft:sand", MinState: 66, DefaultState: 66},
+	{Name: "minecraft:red_sand", MinState: 67, DefaultState: 67},
+	{Name: "minecraft:gravel", MinState: 68, DefaultState: 68},
+	{Name: "minecraft:gold_ore", MinState: 69, DefaultState: 69},
+	{Name: "minecraft:iron_ore", MinState: 70, DefaultState: 70},
+	{Name: "minecraft:coal_ore", MinState: 71, DefaultState: 71},
+	{Name: "minecraft:oak_log", MinState: 73, DefaultState: 74, Properties: []Property{{Name: "axis", Values: []string{"x", "y", "z"}}}},
+	{Name: "minecraft:oak_leaves", MinState: 145, DefaultState: 158, Properties: []Property{{Name: "distance", Values: []string{"1", "2", "3", "4", "5", "6", "7"}}, {Name: "persistent", Values: []string{"true", "false"}}}},
+	{Name: "minecraft:glass", MinState: 231, DefaultState: 231},
+	{Name: "minecraft:sandstone", MinState: 246, DefaultState: 246},
+	{Name: "minecraft:cobweb", MinState: 1341, DefaultState: 1341},
+	{Name: "minecraft:grass", MinState: 1342, DefaultState: 1342},
+	{Name: "minecraft:obsidian", MinState: 1434, DefaultState: 1434},
+	{Name: "minecraft:torch", MinState: 1435, DefaultState: 1435},
+	{Name: "minecraft:oak_stairs", MinState: 1954, DefaultState: 1965, Properties: []Property{{Name: "facing", Values: []string{"north", "south", "west", "east"}}, {Name: "half", Values: []string{"top", "bottom"}}, {Name: "shape", Values: []string{"straight", "inner_left", "inner_right", "outer_left", "outer_right"}}, {Name: "waterlogged", Values: []string{"true", "false"}}}},
+	{Name: "minecraft:chest", MinState: 2034, DefaultState: 2035, Properties: []Property{{Name: "facing", Values: []string{"north", "south", "west", "east"}}, {Name: "type", Values: []string{"single", "left", "right"}}, {Name: "waterlogged", Values: []string{"true", "false"}}}},
+	{Name: "minecraft:oak_sign", MinState: 3382, DefaultState: 3383, Properties: []Property{{Name: "rotation", Values: []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15"}}, {Name: "waterlogged", Values: []string{"true", "false"}}}},
+	{Name: "minecraft:oak_wall_sign", MinState: 3736, DefaultState: 3737, Properties: []Property{{Name: "facing", Values: []string{"north", "south", "west", "east"}}, {Name: "waterlogged", Values: []string{"true", "false"}}}},
+	{Name: "minecraft:snow", MinState: 3921, DefaultState: 3921, Properties: []Property{{Name: "layers", Values: []string{"1", "2", "3", "4", "5", "6", "7", "8"}}}},
+	{Name: "minecraft:ice", MinState: 3929, DefaultState: 3929},
+	{Name: "minecraft:snow_block", MinState: 3930, DefaultState: 3930},
+	{Name: "minecraft:netherrack", MinState: 3999, DefaultState: 3999},
+	{Name: "minecraft:end_stone", MinState: 5158, DefaultState: 5158},
+}
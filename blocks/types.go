@@ -1,9 +1,149 @@
 package blocks
 
-const (
-	Air      = 0
-	Stone    = 1
-	Grass    = 9
-	WaterLow = 35
-	Water    = 39
+//go:generate go run ../cmd/datagen -blocks ../reports/blocks.json -out registry_gen.go
+
+import (
+	"fmt"
+	"sort"
 )
+
+// Grass is the grass block, which the name has always meant here. The block vanilla calls minecraft:grass is
+// ShortGrass.
+const Grass = GrassBlock
+
+// WaterLow is flowing water of level 1, the highest water that isn't a source
+const WaterLow = Water + 1
+
+// State is a global block state ID, which identifies a block together with the values of all its properties
+// (e.g. an oak staircase facing north)
+type State uint16
+
+type Property struct {
+	Name   string
+	Values []string
+}
+
+// Block describes a block type and the range of states that belong to it
+type Block struct {
+	Name         string
+	Properties   []Property
+	MinState     State
+	DefaultState State
+}
+
+func (b *Block) numStates() int {
+	n := 1
+	for _, p := range b.Properties {
+		n *= len(p.Values)
+	}
+	return n
+}
+
+// State returns the state of the block with the given properties. Properties that aren't given keep the value they
+// have in the default state.
+func (b *Block) State(props map[string]string) (State, error) {
+	values := b.PropertiesOf(b.DefaultState)
+	for name, v := range props {
+		if b.property(name) == nil {
+			return 0, fmt.Errorf("block %s: unknown property %s", b.Name, name)
+		}
+		values[name] = v
+	}
+
+	// States are numbered with the last property changing the fastest
+	offset := 0
+	for _, p := range b.Properties {
+		index := indexOf(p.Values, values[p.Name])
+		if index < 0 {
+			return 0, fmt.Errorf("block %s: invalid value %q for property %s", b.Name, values[p.Name], p.Name)
+		}
+		offset = offset*len(p.Values) + index
+	}
+	return b.MinState + State(offset), nil
+}
+
+// PropertiesOf returns the values of every property of the block in state s, which must belong to the block
+func (b *Block) PropertiesOf(s State) map[string]string {
+	props := make(map[string]string, len(b.Properties))
+	offset := int(s - b.MinState)
+	for i := len(b.Properties) - 1; i >= 0; i-- {
+		p := b.Properties[i]
+		props[p.Name] = p.Values[offset%len(p.Values)]
+		offset /= len(p.Values)
+	}
+	return props
+}
+
+func (b *Block) property(name string) *Property {
+	for i := range b.Properties {
+		if b.Properties[i].Name == name {
+			return &b.Properties[i]
+		}
+	}
+	return nil
+}
+
+func indexOf(values []string, v string) int {
+	for i := range values {
+		if values[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+var byName = make(map[string]*Block, len(registry))
+
+func init() {
+	for i := range registry {
+		byName[registry[i].Name] = &registry[i]
+	}
+}
+
+// ByName returns the block with the namespaced name, e.g. minecraft:oak_stairs
+func ByName(name string) (*Block, bool) {
+	b, ok := byName[name]
+	return b, ok
+}
+
+// Get returns the state of the named block with the given properties, e.g.
+//
+//	blocks.Get("minecraft:oak_stairs", map[string]string{"facing": "north"})
+//
+// Properties that aren't given keep the value they have in the block's default state.
+func Get(name string, props map[string]string) (State, error) {
+	b, ok := ByName(name)
+	if !ok {
+		return 0, fmt.Errorf("unknown block %s", name)
+	}
+	return b.State(props)
+}
+
+// MustGet is like Get but panics if the block or any of its properties don't exist
+func MustGet(name string, props map[string]string) State {
+	s, err := Get(name, props)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// BlockOf returns the block that the state belongs to
+func BlockOf(s State) (*Block, bool) {
+	i := sort.Search(len(registry), func(i int) bool {
+		return registry[i].MinState > s
+	}) - 1
+	if i < 0 || int(s-registry[i].MinState) >= registry[i].numStates() {
+		return nil, false
+	}
+	return &registry[i], true
+}
+
+// Lookup returns the name and properties of a block state
+func Lookup(s State) (name string, props map[string]string, ok bool) {
+	b, ok := BlockOf(s)
+	if !ok {
+		return "", nil, false
+	}
+	return b.Name, b.PropertiesOf(s), true
+}
@@ -0,0 +1,66 @@
+package blocks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]string
+		want  State
+	}{
+		{"minecraft:air", nil, Air},
+		{"minecraft:oak_stairs", map[string]string{"facing": "north"}, OakStairs},
+		{"minecraft:oak_stairs", map[string]string{"facing": "north", "half": "top", "waterlogged": "true"}, 1954},
+		{"minecraft:oak_stairs", map[string]string{"facing": "east", "shape": "outer_right"}, 2033},
+		{"minecraft:grass_block", map[string]string{"snowy": "true"}, 8},
+		{"minecraft:grass_block", nil, Grass},
+		{"minecraft:grass", nil, ShortGrass},
+		{"minecraft:water", map[string]string{"level": "1"}, WaterLow},
+		{"minecraft:water", map[string]string{"level": "15"}, 49},
+	}
+	for _, tt := range tests {
+		got, err := Get(tt.name, tt.props)
+		if err != nil {
+			t.Errorf("Get(%s, %v) error = %v", tt.name, tt.props, err)
+		} else if got != tt.want {
+			t.Errorf("Get(%s, %v) = %d, want %d", tt.name, tt.props, got, tt.want)
+		}
+	}
+
+	if _, err := Get("minecraft:not_a_block", nil); err == nil {
+		t.Errorf("Get(minecraft:not_a_block) error = nil, want error")
+	}
+	if _, err := Get("minecraft:oak_stairs", map[string]string{"facing": "up"}); err == nil {
+		t.Errorf("Get(minecraft:oak_stairs, facing=up) error = nil, want error")
+	}
+	if _, err := Get("minecraft:stone", map[string]string{"snowy": "true"}); err == nil {
+		t.Errorf("Get(minecraft:stone, snowy=true) error = nil, want error")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	// Every state must map back to the same state through its name and properties
+	for _, b := range registry {
+		for s := b.MinState; int(s-b.MinState) < b.numStates(); s++ {
+			name, props, ok := Lookup(s)
+			if !ok {
+				t.Fatalf("Lookup(%d) not found", s)
+			}
+			if got, err := Get(name, props); err != nil || got != s {
+				t.Errorf("Get(Lookup(%d)) = %d, %v, want %d", s, got, err, s)
+			}
+		}
+	}
+
+	name, props, _ := Lookup(OakStairs)
+	want := map[string]string{"facing": "north", "half": "bottom", "shape": "straight", "waterlogged": "false"}
+	if name != "minecraft:oak_stairs" || !reflect.DeepEqual(props, want) {
+		t.Errorf("Lookup(OakStairs) = %s, %v, want minecraft:oak_stairs, %v", name, props, want)
+	}
+	if _, _, ok := Lookup(72); ok {
+		t.Errorf("Lookup(72) found, want not found (nether_gold_ore is not in the fixture)")
+	}
+}
@@ -0,0 +1,132 @@
+// Package chat builds chat components, the JSON text format of chat messages, disconnect reasons and the server list.
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Component is a piece of formatted text. Formatting that isn't set is inherited from the parent component.
+type Component struct {
+	Text          string      `json:"text"`
+	Color         string      `json:"color,omitempty"`
+	Bold          *bool       `json:"bold,omitempty"`
+	Italic        *bool       `json:"italic,omitempty"`
+	Underlined    *bool       `json:"underlined,omitempty"`
+	Strikethrough *bool       `json:"strikethrough,omitempty"`
+	Obfuscated    *bool       `json:"obfuscated,omitempty"`
+	Extra         []Component `json:"extra,omitempty"`
+}
+
+// Text returns a component of unformatted text
+func Text(text string) Component {
+	return Component{Text: text}
+}
+
+// Colored returns a component of text in one of the named colors, e.g. red or dark_aqua
+func Colored(text string, color string) Component {
+	return Component{Text: text, Color: color}
+}
+
+// Append adds children to the component, which are shown after its text
+func (c Component) Append(children ...Component) Component {
+	c.Extra = append(append([]Component(nil), c.Extra...), children...)
+	return c
+}
+
+// String returns the component as JSON
+func (c Component) String() string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		panic(err) // only strings and bools, which always marshal
+	}
+	return string(b)
+}
+
+// PlainText returns the text of the component and its children without formatting
+func (c Component) PlainText() string {
+	var b strings.Builder
+	c.writePlain(&b)
+	return b.String()
+}
+
+func (c Component) writePlain(b *strings.Builder) {
+	b.WriteString(c.Text)
+	for _, child := range c.Extra {
+		child.writePlain(b)
+	}
+}
+
+var legacyColors = map[rune]string{
+	'0': "black",
+	'1': "dark_blue",
+	'2': "dark_green",
+	'3': "dark_aqua",
+	'4': "dark_red",
+	'5': "dark_purple",
+	'6': "gold",
+	'7': "gray",
+	'8': "dark_gray",
+	'9': "blue",
+	'a': "green",
+	'b': "aqua",
+	'c': "red",
+	'd': "light_purple",
+	'e': "yellow",
+	'f': "white",
+}
+
+// FromLegacy converts text with legacy formatting codes like §c (red) or §l (bold) to a component, since that's how
+// formatted text is written in places like server.properties. A color code resets the formatting, like it does in
+// vanilla.
+func FromLegacy(text string) Component {
+	var root Component
+	var current Component
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			current.Text = b.String()
+			root.Extra = append(root.Extra, current)
+			b.Reset()
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '§' || i == len(runes)-1 {
+			b.WriteRune(runes[i])
+			continue
+		}
+		i++
+		code := runes[i]
+		if code >= 'A' && code <= 'Z' {
+			code += 'a' - 'A'
+		}
+		flush()
+		on := true
+		switch code {
+		case 'k':
+			current.Obfuscated = &on
+		case 'l':
+			current.Bold = &on
+		case 'm':
+			current.Strikethrough = &on
+		case 'n':
+			current.Underlined = &on
+		case 'o':
+			current.Italic = &on
+		case 'r':
+			current = Component{}
+		default:
+			if color, ok := legacyColors[code]; ok {
+				current = Component{Color: color}
+			}
+		}
+	}
+	flush()
+
+	if len(root.Extra) == 1 {
+		return root.Extra[0]
+	}
+	return root
+}
@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"testing"
+)
+
+func TestFromLegacy(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"A Minecraft Server", `{"text":"A Minecraft Server"}`},
+		{"§cRed", `{"text":"Red","color":"red"}`},
+		{"Plain §6§lGold bold §oand italic§r plain§",
+			`{"text":"","extra":[{"text":"Plain "},{"text":"Gold bold ","color":"gold","bold":true},` +
+				`{"text":"and italic","color":"gold","bold":true,"italic":true},{"text":" plain§"}]}`},
+		{"§lBold §aresets", `{"text":"","extra":[{"text":"Bold ","bold":true},{"text":"resets","color":"green"}]}`},
+	}
+	for _, tt := range tests {
+		if got := FromLegacy(tt.text).String(); got != tt.want {
+			t.Errorf("FromLegacy(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestComponent_PlainText(t *testing.T) {
+	c := Colored("Hello", "red").Append(Text(", "), FromLegacy("§lworld"))
+	if got := c.PlainText(); got != "Hello, world" {
+		t.Errorf("PlainText() = %q, want %q", got, "Hello, world")
+	}
+}
@@ -0,0 +1,130 @@
+package chunks
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+)
+
+// BlockEntity holds the data for a block that can't be described by its block state alone, like the text on a sign
+// or the items in a chest.
+type BlockEntity struct {
+	Pos pstn.Block // absolute position in the world
+	ID  string     // e.g. minecraft:sign
+
+	// Data is the NBT payload of the block entity without the id, x, y and z tags which are derived from the fields
+	// above. See proto.WriteCompound for the value types that are supported.
+	Data map[string]interface{}
+}
+
+// NewSign creates the block entity for a sign with up to four lines of plain text
+func NewSign(lines ...string) *BlockEntity {
+	be := &BlockEntity{ID: "minecraft:sign", Data: map[string]interface{}{
+		"Color": "black",
+	}}
+	for i := 0; i < 4; i++ {
+		var text string
+		if i < len(lines) {
+			text = lines[i]
+		}
+		line, _ := json.Marshal(map[string]string{"text": text})
+		be.Data[fmt.Sprintf("Text%d", i+1)] = string(line)
+	}
+	return be
+}
+
+// NBT returns the full compound for the block entity as it is sent to clients and stored in saves
+func (b *BlockEntity) NBT() map[string]interface{} {
+	c := make(map[string]interface{}, len(b.Data)+4)
+	for k, v := range b.Data {
+		c[k] = v
+	}
+	c["id"] = b.ID
+	c["x"] = b.Pos.X
+	c["y"] = b.Pos.Y
+	c["z"] = b.Pos.Z
+	return c
+}
+
+// updateActions are the action IDs of the Block Entity Data packet for every block entity the client needs to know
+// about. Block entities that aren't in here (chests, furnaces...) are only ever sent with the chunk.
+var updateActions = map[string]uint8{
+	"minecraft:mob_spawner":     1,
+	"minecraft:command_block":   2,
+	"minecraft:beacon":          3,
+	"minecraft:skull":           4,
+	"minecraft:conduit":         5,
+	"minecraft:banner":          6,
+	"minecraft:structure_block": 7,
+	"minecraft:end_gateway":     8,
+	"minecraft:sign":            9,
+	"minecraft:bed":             11,
+	"minecraft:jigsaw":          12,
+	"minecraft:campfire":        13,
+	"minecraft:beehive":         14,
+}
+
+// UpdateAction returns the action of the Block Entity Data packet for this block entity and whether the client
+// should be sent an update when it changes at all.
+func (b *BlockEntity) UpdateAction() (uint8, bool) {
+	action, ok := updateActions[b.ID]
+	return action, ok
+}
+
+func (c *ChunkColumn) blockPos(x int, y int, z int) pstn.Block {
+	return pstn.Block{X: c.Pos.X*Width + int32(x), Y: int32(y), Z: c.Pos.Z*Depth + int32(z)}
+}
+
+// BlockEntityAt returns the block entity at x, y, z in the column, or nil if there is none
+func (c *ChunkColumn) BlockEntityAt(x int, y int, z int) *BlockEntity {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.blockEntities[c.blockPos(x, y, z)]
+}
+
+// SetBlockEntity places the block entity at x, y, z in the column, replacing any that was there before. The position of
+// the block entity is updated to match. It's sent to clients with the next changes, so it has to be set after its
+// block.
+func (c *ChunkColumn) SetBlockEntity(x int, y int, z int, be *BlockEntity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.blockEntities == nil {
+		c.blockEntities = make(map[pstn.Block]*BlockEntity)
+	}
+	be.Pos = c.blockPos(x, y, z)
+	c.blockEntities[be.Pos] = be
+	c.changes.recordBlockEntity(be.Pos)
+	c.version++
+}
+
+// RemoveBlockEntity removes the block entity at x, y, z in the column. Clients aren't told, they remove block entities
+// themselves when the block changes.
+func (c *ChunkColumn) RemoveBlockEntity(x int, y int, z int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.blockEntities, c.blockPos(x, y, z))
+	c.version++
+}
+
+// BlockEntities returns every block entity in the column in no particular order
+func (c *ChunkColumn) BlockEntities() []*BlockEntity {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	all := make([]*BlockEntity, 0, len(c.blockEntities))
+	for _, be := range c.blockEntities {
+		all = append(all, be)
+	}
+	return all
+}
+
+type BlockEntityDataPacket struct {
+	Entity *BlockEntity
+}
+
+func (p BlockEntityDataPacket) EncodeTo(e *proto.PacketEncoder) {
+	action, _ := p.Entity.UpdateAction()
+	e.WritePosition(p.Entity.Pos)
+	e.WriteU8(action)
+	e.WriteCompound(p.Entity.NBT())
+}
@@ -0,0 +1,143 @@
+package chunks
+
+import (
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+)
+
+// FullResendThreshold is the number of changed blocks in a column past which it's cheaper to resend the whole chunk
+// than to send every block change on its own.
+const FullResendThreshold = 1024
+
+// ColumnChanges are the blocks that changed in a column since the last time changes were taken from it
+type ColumnChanges struct {
+	Chunk *ChunkColumn
+	// Full is set when so many blocks changed that the whole chunk should be resent instead
+	Full bool
+	// Sections has the indices (see ChunkSection.index) of every changed block in each section
+	Sections [SectionsInChunk][]int
+	// BlockEntities are the block entities that were set since the last changes, which are sent after the blocks.
+	// They're copies that share their Data, like in Clone.
+	BlockEntities []*BlockEntity
+}
+
+type changeTracker struct {
+	enabled       bool
+	sections      [SectionsInChunk]map[int]struct{}
+	count         int
+	blockEntities map[pstn.Block]struct{}
+}
+
+func (t *changeTracker) record(sectionY int, index int) {
+	if !t.enabled || t.count > FullResendThreshold {
+		return
+	}
+	if t.sections[sectionY] == nil {
+		t.sections[sectionY] = make(map[int]struct{})
+	}
+	if _, ok := t.sections[sectionY][index]; !ok {
+		t.sections[sectionY][index] = struct{}{}
+		t.count++
+	}
+}
+
+func (t *changeTracker) recordBlockEntity(pos pstn.Block) {
+	if !t.enabled {
+		return
+	}
+	if t.blockEntities == nil {
+		t.blockEntities = make(map[pstn.Block]struct{})
+	}
+	t.blockEntities[pos] = struct{}{}
+}
+
+func (t *changeTracker) empty() bool {
+	return t.count == 0 && len(t.blockEntities) == 0
+}
+
+// TrackChanges starts recording every block that changes in the column so the changes can be sent to clients
+// with TakeChanges. Chunks are not tracked while they are being generated.
+func (c *ChunkColumn) TrackChanges() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes.enabled = true
+}
+
+func (c *ChunkColumn) HasChanges() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.changes.empty()
+}
+
+// TakeChanges returns the blocks and block entities that changed since the last call and resets the tracked changes.
+// It returns nil if nothing changed.
+func (c *ChunkColumn) TakeChanges() *ColumnChanges {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.changes.empty() {
+		return nil
+	}
+	changes := &ColumnChanges{Chunk: c, Full: c.changes.count > FullResendThreshold}
+	if !changes.Full {
+		for y, sec := range c.changes.sections {
+			for index := range sec {
+				changes.Sections[y] = append(changes.Sections[y], index)
+			}
+		}
+		// block entities that were removed since are gone on the client as well when their block changed
+		for pos := range c.changes.blockEntities {
+			if be, ok := c.blockEntities[pos]; ok {
+				copied := *be
+				changes.BlockEntities = append(changes.BlockEntities, &copied)
+			}
+		}
+	}
+	c.changes = changeTracker{enabled: c.changes.enabled}
+	return changes
+}
+
+// BlockChangePacket (Block Change) updates a single block for the client
+type BlockChangePacket struct {
+	Pos   pstn.Block
+	State BlockState
+}
+
+func (p BlockChangePacket) EncodeTo(e *proto.PacketEncoder) {
+	e.WritePosition(p.Pos)
+	e.WriteVar32(int32(p.State))
+}
+
+// NewBlockChangePacket creates the packet for the block at index in section sectionY of the column
+func NewBlockChangePacket(c *ChunkColumn, sectionY int, index int) BlockChangePacket {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	sec := &c.Sections[sectionY]
+	x, y, z := index&0xf, index>>8, (index>>4)&0xf
+	return BlockChangePacket{
+		Pos:   c.blockPos(x, sectionY*SectionHeight+y, z),
+		State: sec.blocks[index],
+	}
+}
+
+// MultiBlockChangePacket (Multi Block Change) updates several blocks within the same chunk section
+type MultiBlockChangePacket struct {
+	Chunk    *ChunkColumn
+	SectionY int
+	Blocks   []int // indices of the blocks in the section
+}
+
+func (p MultiBlockChangePacket) EncodeTo(e *proto.PacketEncoder) {
+	sectionPos := (int64(p.Chunk.Pos.X)&0x3FFFFF)<<42 | (int64(p.Chunk.Pos.Z)&0x3FFFFF)<<20 | int64(p.SectionY)&0xFFFFF
+	e.WriteI64(sectionPos)
+	e.WriteBool(true) // inverse of trust edges in the light packet
+
+	p.Chunk.mu.RLock()
+	defer p.Chunk.mu.RUnlock()
+	sec := &p.Chunk.Sections[p.SectionY]
+	e.WriteVar32(int32(len(p.Blocks)))
+	for _, index := range p.Blocks {
+		x, y, z := index&0xf, index>>8, (index>>4)&0xf
+		pos := int64(x<<8 | z<<4 | y)
+		e.WriteVar64(int64(sec.blocks[index])<<12 | pos)
+	}
+}
@@ -1,8 +1,10 @@
 package chunks
 
 import (
+	"github.com/masp/mcgo/biome"
 	"github.com/masp/mcgo/blocks"
 	"github.com/masp/mcgo/pstn"
+	"sync"
 )
 
 const (
@@ -12,9 +14,13 @@ const (
 	SectionHeight   = 16
 	SectionsInChunk = Height / SectionHeight
 	BlocksInSection = Width * SectionHeight * Depth
+
+	// Biomes are stored in cells of 4x4x4 blocks, which is the resolution the client expects them in
+	BiomeCellSize = 4
+	BiomesInChunk = (Width / BiomeCellSize) * (Height / BiomeCellSize) * (Depth / BiomeCellSize)
 )
 
-type BlockState uint16 // TODO: This should definitely not be an int
+type BlockState = blocks.State
 
 type ChunkLighting struct {
 	SkyLight   LightingArray
@@ -25,6 +31,7 @@ type ChunkSection struct {
 	blocks            [BlocksInSection]BlockState
 	Lighting          ChunkLighting
 	totalNonAirBlocks int
+	unknown           *unknownBlocks // nil if every block is known
 }
 
 func (s *ChunkSection) index(x int, y int, z int) int {
@@ -43,39 +50,193 @@ func (s *ChunkSection) SetBlockAt(x int, y int, z int, newBlock BlockState) {
 		s.totalNonAirBlocks--
 	}
 	s.blocks[s.index(x, y, z)] = newBlock
+	s.forgetUnknownBlock(x, y, z)
 }
 
 func (s *ChunkSection) IsAir() bool {
 	return s.totalNonAirBlocks == 0
 }
 
+// ChunkColumn is a 16x256x16 column of blocks. Its methods are safe to call from several goroutines, but the
+// sections must only be accessed directly while nothing else has the column yet, like while it's being generated or
+// loaded. Use Clone to read them after that.
 type ChunkColumn struct {
 	Pos pstn.Chunk
 
 	Sections    [SectionsInChunk]ChunkSection
 	VoidSection ChunkLighting // y=-16 to y=-1
 	SkySection  ChunkLighting // y=256 to y=271
+
+	mu            sync.RWMutex // guards everything but Pos
+	biomes        [BiomesInChunk]int32
+	heightmaps    map[string][]int64 // the heightmaps the column was loaded with, until a block changes
+	blockEntities map[pstn.Block]*BlockEntity
+	changes       changeTracker
+	version       uint64     // incremented on every change
+	savedVersion  uint64     // version when the column was last saved
+	saveMu        sync.Mutex // held while the column is saved, so saves happen one after the other
 }
 
 func NewChunk(pos pstn.Chunk) *ChunkColumn {
-	return &ChunkColumn{Pos: pos}
+	c := &ChunkColumn{Pos: pos}
+	c.FillBiome(biome.PlainsID)
+	c.ClearDirty()
+	return c
 }
 
 func (c *ChunkColumn) BlockAt(x int, y int, z int) BlockState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.Sections[y/16].BlockAt(x, y, z)
 }
 
+// SetBlockAt changes the block at x, y, z. The block entity there is removed when the block changes, since it belonged
+// to the block that was replaced.
 func (c *ChunkColumn) SetBlockAt(x int, y int, z int, newBlock BlockState) {
-	c.Sections[y/16].SetBlockAt(x, y, z, newBlock)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sec := &c.Sections[y/16]
+	if sec.BlockAt(x, y, z) == newBlock {
+		return
+	}
+	sec.SetBlockAt(x, y, z, newBlock)
+	delete(c.blockEntities, c.blockPos(x, y, z))
+	c.changes.record(y/16, sec.index(x, y, z))
+	c.heightmaps = nil
+	c.version++
+}
+
+// Dirty returns true if the column changed since it was last saved (or created)
+func (c *ChunkColumn) Dirty() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version != c.savedVersion
+}
+
+// ClearDirty marks the column as saved
+func (c *ChunkColumn) ClearDirty() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.savedVersion = c.version
+}
+
+// Save passes a snapshot of the column to write, and marks the column as saved as of the snapshot if write succeeds.
+// Saves of the same column never run at the same time, so an older snapshot can't overwrite a newer one.
+func (c *ChunkColumn) Save(write func(snapshot *ChunkColumn) error) error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	snapshot := c.Clone()
+	if err := write(snapshot); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if snapshot.version > c.savedVersion {
+		c.savedVersion = snapshot.version
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// Clone returns a copy of the column that nothing else has access to, so its sections can be read directly. Block
+// entities are copied as well, but they share their Data. Changes aren't tracked in the copy.
+func (c *ChunkColumn) Clone() *ChunkColumn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	clone := &ChunkColumn{
+		Pos:          c.Pos,
+		Sections:     c.Sections,
+		VoidSection:  c.VoidSection,
+		SkySection:   c.SkySection,
+		biomes:       c.biomes,
+		heightmaps:   c.heightmaps,
+		version:      c.version,
+		savedVersion: c.savedVersion,
+	}
+	for i := range clone.Sections {
+		if sec := &clone.Sections[i]; sec.unknown != nil {
+			sec.unknown = sec.unknown.clone()
+		}
+	}
+	if c.blockEntities != nil {
+		clone.blockEntities = make(map[pstn.Block]*BlockEntity, len(c.blockEntities))
+		for pos, be := range c.blockEntities {
+			copied := *be
+			clone.blockEntities[pos] = &copied
+		}
+	}
+	return clone
+}
+
+func biomeIndex(x int, y int, z int) int {
+	return (y/BiomeCellSize)<<4 | (z/BiomeCellSize)<<2 | x/BiomeCellSize
 }
 
+// BiomeAt returns the biome ID of the 4x4x4 cell that contains the block at x, y, z
+func (c *ChunkColumn) BiomeAt(x int, y int, z int) int32 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.biomes[biomeIndex(x, y, z)]
+}
+
+// SetBiomeAt sets the biome ID of the whole 4x4x4 cell that contains the block at x, y, z
+func (c *ChunkColumn) SetBiomeAt(x int, y int, z int, id int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.biomes[biomeIndex(x, y, z)] = id
+	c.version++
+}
+
+// FillBiome sets every biome cell in the column to the same biome ID
+func (c *ChunkColumn) FillBiome(id int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.biomes {
+		c.biomes[i] = id
+	}
+	c.version++
+}
+
+// MotionBlocking is the heightmap of the highest blocks that block movement, which is the one clients need
+const MotionBlocking = "MOTION_BLOCKING"
+
+// HeightmapLongs is the length of a heightmap, which has a 9 bit height for every block column packed into longs
+const HeightmapLongs = (Width*Depth + 6) / 7
+
 type Heightmap struct {
 	MotionBlocking []int64 `nbt:"MOTION_BLOCKING"`
 }
 
+// SetHeightmaps keeps the heightmaps the column was saved with, by their name like MotionBlocking. They're used
+// instead of computing them from the blocks, until a block in the column changes. Heightmaps that don't have
+// HeightmapLongs longs are ignored.
+func (c *ChunkColumn) SetHeightmaps(heightmaps map[string][]int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heightmaps = make(map[string][]int64, len(heightmaps))
+	for name, data := range heightmaps {
+		if len(data) == HeightmapLongs {
+			c.heightmaps[name] = data
+		}
+	}
+}
+
+// Heightmaps returns the heightmaps set with SetHeightmaps, or nil if a block changed since then
+func (c *ChunkColumn) Heightmaps() map[string][]int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.heightmaps
+}
+
+// HighestBlock returns the height of the highest block that isn't air in a column of the chunk, or 0 if there is none
+func (c *ChunkColumn) HighestBlock(x int, z int) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.highestBlock(x, z)
+}
+
 func (c *ChunkColumn) highestBlock(x int, z int) int {
 	for y := Height - 1; y >= 0; y-- {
-		if c.BlockAt(x, y, z) != blocks.Air {
+		if c.Sections[y/16].BlockAt(x, y, z) != blocks.Air {
 			return y
 		}
 	}
@@ -83,6 +244,15 @@ func (c *ChunkColumn) highestBlock(x int, z int) int {
 }
 
 func (c *ChunkColumn) HeightMap() Heightmap {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.heightMap()
+}
+
+func (c *ChunkColumn) heightMap() Heightmap {
+	if loaded, ok := c.heightmaps[MotionBlocking]; ok {
+		return Heightmap{MotionBlocking: loaded}
+	}
 	highestBlocks := make([]BlockState, Width*Depth)
 	for x := 0; x < Width; x++ {
 		for z := 0; z < Depth; z++ {
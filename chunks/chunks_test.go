@@ -1,8 +1,13 @@
 package chunks
 
 import (
+	"bytes"
+	"github.com/masp/mcgo/biome"
 	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/proto"
 	"github.com/masp/mcgo/pstn"
+	"io"
+	"reflect"
 	"testing"
 )
 
@@ -23,3 +28,224 @@ func TestChunkSection_SetBlockAt(t *testing.T) {
 		t.Errorf("got section 0 IsAir() = false after block change, want IsAir() = true")
 	}
 }
+
+func TestChunkColumn_SetBiomeAt(t *testing.T) {
+	c := NewChunk(pstn.Chunk{})
+	const desert = 2
+	c.SetBiomeAt(5, 70, 9, desert)
+
+	// Every block in the same 4x4x4 cell shares the biome
+	for x := 4; x < 8; x++ {
+		for y := 68; y < 72; y++ {
+			for z := 8; z < 12; z++ {
+				if got := c.BiomeAt(x, y, z); got != desert {
+					t.Errorf("BiomeAt(%d, %d, %d) = %d, want %d", x, y, z, got, desert)
+				}
+			}
+		}
+	}
+	if got := c.BiomeAt(3, 70, 9); got != biome.PlainsID {
+		t.Errorf("BiomeAt(3, 70, 9) = %d, want %d", got, biome.PlainsID)
+	}
+	if got := c.BiomeAt(5, 72, 9); got != biome.PlainsID {
+		t.Errorf("BiomeAt(5, 72, 9) = %d, want %d", got, biome.PlainsID)
+	}
+}
+
+func TestChunkColumn_SetBlockEntity(t *testing.T) {
+	c := NewChunk(pstn.Chunk{X: -1, Z: 2})
+	sign := NewSign("hello", "world")
+	c.SetBlockEntity(3, 64, 15, sign)
+
+	if want := (pstn.Block{X: -13, Y: 64, Z: 47}); sign.Pos != want {
+		t.Errorf("sign.Pos = %v, want %v", sign.Pos, want)
+	}
+	if got := c.BlockEntityAt(3, 64, 15); got != sign {
+		t.Errorf("BlockEntityAt(3, 64, 15) = %v, want %v", got, sign)
+	}
+	if got := sign.Data["Text2"]; got != `{"text":"world"}` {
+		t.Errorf("sign Text2 = %v, want %v", got, `{"text":"world"}`)
+	}
+	if action, ok := sign.UpdateAction(); !ok || action != 9 {
+		t.Errorf("sign.UpdateAction() = %d, %t, want 9, true", action, ok)
+	}
+
+	c.RemoveBlockEntity(3, 64, 15)
+	if got := c.BlockEntityAt(3, 64, 15); got != nil {
+		t.Errorf("BlockEntityAt(3, 64, 15) after remove = %v, want nil", got)
+	}
+
+	// The block entity belongs to the block, so it's gone when the block is broken or replaced
+	c.SetBlockAt(3, 64, 15, blocks.OakSign)
+	c.SetBlockEntity(3, 64, 15, NewSign("hello"))
+	c.SetBlockAt(3, 64, 15, blocks.OakSign)
+	if c.BlockEntityAt(3, 64, 15) == nil {
+		t.Errorf("setting the same block removed its block entity")
+	}
+	c.SetBlockAt(3, 64, 15, blocks.Air)
+	if got := c.BlockEntityAt(3, 64, 15); got != nil {
+		t.Errorf("BlockEntityAt(3, 64, 15) after breaking the block = %v, want nil", got)
+	}
+}
+
+func TestBlockEntityDataPacket_EncodeTo(t *testing.T) {
+	sign := NewSign("hi")
+	sign.Pos = pstn.Block{X: -5, Y: 70, Z: 12}
+	d := proto.NewPacketDecoder(bytes.NewReader(proto.EncodePacket(0x09, BlockEntityDataPacket{Entity: sign})))
+	d.ReadVar32()
+
+	if got := d.ReadPosition(); got != sign.Pos {
+		t.Errorf("position = %v, want %v", got, sign.Pos)
+	}
+	if action := d.ReadU8(); action != 9 {
+		t.Errorf("action = %d, want 9 for signs", action)
+	}
+	var got map[string]interface{}
+	if !d.ReadNBT(&got) || !reflect.DeepEqual(got, sign.NBT()) {
+		t.Errorf("NBT = %v, want %v", got, sign.NBT())
+	}
+}
+
+func TestChunkColumn_EncodeTo(t *testing.T) {
+	c := NewChunk(pstn.Chunk{X: 1, Z: -1})
+	c.SetBlockAt(2, 3, 4, blocks.OakSign)
+	c.SetBlockEntity(2, 3, 4, NewSign("in", "the", "chunk"))
+	d := proto.NewPacketDecoder(bytes.NewReader(proto.EncodePacket(0x20, c)))
+	d.ReadVar32()
+
+	if x, z := d.ReadI32(), d.ReadI32(); x != 1 || z != -1 {
+		t.Errorf("chunk position = %d, %d, want 1, -1", x, z)
+	}
+	if !d.ReadBool() {
+		t.Errorf("chunk isn't sent as a full chunk")
+	}
+	if mask := d.ReadVar32(); mask != 1 {
+		t.Errorf("primary bitmask = %b, want only the first section", mask)
+	}
+	var heightmaps map[string]interface{}
+	d.ReadNBT(&heightmaps)
+	for i, n := 0, int(d.ReadVar32()); i < n; i++ {
+		d.ReadVar32() // biomes
+	}
+	sections := make([]byte, d.ReadVar32())
+	if _, err := io.ReadFull(d, sections); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := d.ReadVar32(); n != 1 {
+		t.Fatalf("chunk has %d block entities, want 1", n)
+	}
+	var got map[string]interface{}
+	want := c.BlockEntityAt(2, 3, 4).NBT()
+	if !d.ReadNBT(&got) || !reflect.DeepEqual(got, want) {
+		t.Errorf("block entity = %v, want %v", got, want)
+	}
+	if want["x"] != int32(18) || want["z"] != int32(-12) {
+		t.Errorf("block entity at %v, %v, want the absolute position 18, -12", want["x"], want["z"])
+	}
+}
+
+func TestChunkColumn_TakeChanges(t *testing.T) {
+	c := NewChunk(pstn.Chunk{})
+	c.SetBlockAt(0, 0, 0, blocks.Stone)
+	if c.HasChanges() {
+		t.Errorf("got HasChanges() = true before tracking, want false")
+	}
+
+	c.TrackChanges()
+	c.SetBlockAt(1, 2, 3, blocks.Stone)
+	c.SetBlockAt(1, 2, 3, blocks.Grass) // same block twice is one change
+	c.SetBlockAt(4, 20, 5, blocks.Stone)
+	c.SetBlockAt(0, 0, 0, blocks.Stone) // unchanged block isn't recorded
+
+	changes := c.TakeChanges()
+	if changes == nil || changes.Full {
+		t.Fatalf("TakeChanges() = %v, want partial changes", changes)
+	}
+	if got := changes.Sections[0]; !reflect.DeepEqual(got, []int{2<<8 | 3<<4 | 1}) {
+		t.Errorf("changes in section 0 = %v, want [%d]", got, 2<<8|3<<4|1)
+	}
+	if got := changes.Sections[1]; !reflect.DeepEqual(got, []int{4<<8 | 5<<4 | 4}) {
+		t.Errorf("changes in section 1 = %v, want [%d]", got, 4<<8|5<<4|4)
+	}
+	if c.TakeChanges() != nil {
+		t.Errorf("TakeChanges() after taking = non-nil, want nil")
+	}
+
+	// Block entities are sent with the changes even when no block changed
+	c.SetBlockEntity(1, 2, 3, NewSign("changed"))
+	c.SetBlockEntity(4, 20, 5, NewSign("removed"))
+	c.RemoveBlockEntity(4, 20, 5)
+	changes = c.TakeChanges()
+	sign := pstn.Block{X: 1, Y: 2, Z: 3}
+	if changes == nil || len(changes.BlockEntities) != 1 || changes.BlockEntities[0].Pos != sign {
+		t.Fatalf("TakeChanges() = %+v, want the sign at 1, 2, 3", changes)
+	}
+
+	for x := 0; x < Width; x++ {
+		for z := 0; z < Depth; z++ {
+			for y := 100; y < 105; y++ {
+				c.SetBlockAt(x, y, z, blocks.Stone)
+			}
+		}
+	}
+	if changes := c.TakeChanges(); changes == nil || !changes.Full {
+		t.Errorf("TakeChanges() after %d changes = %v, want full resend", Width*Depth*5, changes)
+	}
+}
+
+func TestChunkColumn_Save(t *testing.T) {
+	c := NewChunk(pstn.Chunk{})
+	c.SetBlockAt(0, 0, 0, blocks.Stone)
+	if !c.Dirty() {
+		t.Fatalf("got Dirty() = false after a change, want true")
+	}
+
+	err := c.Save(func(snapshot *ChunkColumn) error {
+		if snapshot.Sections[0].BlockAt(0, 0, 0) != blocks.Stone {
+			t.Errorf("snapshot is missing the change")
+		}
+		// Changes made while saving aren't in the snapshot, so they still have to be saved afterwards
+		c.SetBlockAt(1, 0, 0, blocks.Stone)
+		if snapshot.Sections[0].BlockAt(1, 0, 0) != blocks.Air {
+			t.Errorf("change to the column showed up in the snapshot")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Dirty() {
+		t.Errorf("got Dirty() = false with a change made while saving, want true")
+	}
+
+	if err := c.Save(func(*ChunkColumn) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if c.Dirty() {
+		t.Errorf("got Dirty() = true after saving, want false")
+	}
+}
+
+func TestChunkColumn_SetHeightmaps(t *testing.T) {
+	c := NewChunk(pstn.Chunk{})
+	c.SetBlockAt(0, 10, 0, blocks.Stone)
+	computed := c.HeightMap().MotionBlocking
+
+	saved := make([]int64, HeightmapLongs)
+	saved[0] = 42
+	c.SetHeightmaps(map[string][]int64{MotionBlocking: saved})
+	if got := c.HeightMap().MotionBlocking; !reflect.DeepEqual(got, saved) {
+		t.Errorf("HeightMap() = %v, want the saved heightmap", got)
+	}
+
+	// A changed block makes the saved heightmaps outdated
+	c.SetBlockAt(0, 10, 0, blocks.Air)
+	c.SetBlockAt(0, 10, 0, blocks.Stone)
+	if c.Heightmaps() != nil {
+		t.Errorf("Heightmaps() = %v after a block changed, want nil", c.Heightmaps())
+	}
+	if got := c.HeightMap().MotionBlocking; !reflect.DeepEqual(got, computed) {
+		t.Errorf("HeightMap() = %v, want it computed from the blocks again", got)
+	}
+}
@@ -2,7 +2,6 @@ package chunks
 
 import (
 	"bytes"
-	"github.com/masp/mcgo/biome"
 	"github.com/masp/mcgo/proto"
 )
 
@@ -21,18 +20,19 @@ const (
 )
 
 func (c *ChunkColumn) EncodeTo(enc *proto.PacketEncoder) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	enc.WriteI32(c.Pos.X)
 	enc.WriteI32(c.Pos.Z)
 	fullChunk := true
 	enc.WriteBool(fullChunk)
 	enc.WriteVar32(c.primaryBitmask())
-	enc.WriteNBT(c.HeightMap())
+	enc.WriteNBT(c.heightMap())
 	if fullChunk {
 		// Even though we specify the length, this must always be 1024 to match what the client expects
-		const biomeSize = 1024
-		enc.WriteVar32(biomeSize)
-		for i := 0; i < biomeSize; i++ {
-			enc.WriteVar32(biome.PlainsID)
+		enc.WriteVar32(BiomesInChunk)
+		for _, id := range c.biomes {
+			enc.WriteVar32(id)
 		}
 	}
 
@@ -46,7 +46,10 @@ func (c *ChunkColumn) EncodeTo(enc *proto.PacketEncoder) {
 	enc.WriteVar32(int32(secBuffer.Len()))
 	_, _ = enc.Write(secBuffer.Bytes())
 
-	enc.WriteVar32(0) // Block entities
+	enc.WriteVar32(int32(len(c.blockEntities)))
+	for _, be := range c.blockEntities {
+		enc.WriteCompound(be.NBT())
+	}
 }
 
 func (s *ChunkSection) EncodeTo(enc *proto.PacketEncoder) {
@@ -79,6 +82,9 @@ func (c ChunkLightingPacket) EncodeTo(e *proto.PacketEncoder) {
 	e.WriteVar32(c.Chunk.Pos.Z)
 	e.WriteBool(false) // trust edges
 
+	c.Chunk.mu.RLock()
+	defer c.Chunk.mu.RUnlock()
+
 	// TODO: Don't send every chunk as fully lit
 	skymask := c.Chunk.skyLightBitmask()
 	blockmask := c.Chunk.blockLightBitmask()
@@ -73,3 +73,23 @@ func (arr *LightingArray) Init(data []byte) {
 		}
 	}
 }
+
+// UnpackPaddedBlockArray reads n values back out of the longs of a padded block array with the given bits per item
+func UnpackPaddedBlockArray(bitsPerItem int, data []int64, n int) []BlockState {
+	if bitsPerItem >= 64 || bitsPerItem == 0 {
+		panic("Invalid bits per item, must be between [1-64]")
+	}
+
+	itemsPerLong := 64 / bitsPerItem
+	mask := uint64(1)<<bitsPerItem - 1
+	values := make([]BlockState, n)
+	for i := range values {
+		longIndex := i / itemsPerLong
+		bitPosStart := (i % itemsPerLong) * bitsPerItem
+		if longIndex >= len(data) {
+			break
+		}
+		values[i] = BlockState(uint64(data[longIndex]) >> bitPosStart & mask)
+	}
+	return values
+}
@@ -49,6 +49,9 @@ func TestNewPaddedBlockArray(t *testing.T) {
 			if got := NewPaddedBlockArray(tt.args.bitsPerItem, tt.args.values); !reflect.DeepEqual(got.Data, tt.want) {
 				t.Errorf("CreatePackedArray() = %064b, want %064b", got.Data, tt.want)
 			}
+			if got := UnpackPaddedBlockArray(tt.args.bitsPerItem, tt.want, len(tt.args.values)); !reflect.DeepEqual(got, tt.args.values) {
+				t.Errorf("UnpackPaddedBlockArray() = %v, want %v", got, tt.args.values)
+			}
 		})
 	}
 	// 0000000000100000100001100011000101001000010000011000100001000001
@@ -0,0 +1,89 @@
+package chunks
+
+import "github.com/masp/mcgo/blocks"
+
+// UnknownBlock is a block of a saved chunk that isn't in the block registry, with the name and properties it was saved
+// with. It's air in the column, but it's kept so the chunk is saved with the block until it's replaced.
+type UnknownBlock struct {
+	Name       string
+	Properties map[string]string
+}
+
+// unknownBlocks are the unknown blocks of a section
+type unknownBlocks struct {
+	palette []*UnknownBlock
+	blocks  [BlocksInSection]uint16 // index in palette + 1, or 0 where the block is known
+	count   int
+}
+
+func (u *unknownBlocks) clone() *unknownBlocks {
+	copied := *u
+	copied.palette = append([]*UnknownBlock(nil), u.palette...)
+	return &copied
+}
+
+// UnknownBlockAt returns the unknown block at x, y, z in the section, or nil if the block there is known
+func (s *ChunkSection) UnknownBlockAt(x int, y int, z int) *UnknownBlock {
+	if s.unknown == nil {
+		return nil
+	}
+	if i := s.unknown.blocks[s.index(x, y, z)]; i > 0 {
+		return s.unknown.palette[i-1]
+	}
+	return nil
+}
+
+// HasUnknownBlocks returns true if there are unknown blocks in the section, which then has to be saved even if it's
+// all air
+func (s *ChunkSection) HasUnknownBlocks() bool {
+	return s.unknown != nil
+}
+
+func (s *ChunkSection) setUnknownBlock(x int, y int, z int, b *UnknownBlock) {
+	s.SetBlockAt(x, y, z, blocks.Air) // forgets the unknown block that was there
+	if s.unknown == nil {
+		s.unknown = &unknownBlocks{}
+	}
+	index := -1
+	for i, known := range s.unknown.palette {
+		if known == b {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		s.unknown.palette = append(s.unknown.palette, b)
+		index = len(s.unknown.palette) - 1
+	}
+	s.unknown.blocks[s.index(x, y, z)] = uint16(index + 1)
+	s.unknown.count++
+}
+
+// forgetUnknownBlock drops the unknown block at x, y, z once another block is set there
+func (s *ChunkSection) forgetUnknownBlock(x int, y int, z int) {
+	if s.unknown == nil || s.unknown.blocks[s.index(x, y, z)] == 0 {
+		return
+	}
+	s.unknown.blocks[s.index(x, y, z)] = 0
+	s.unknown.count--
+	if s.unknown.count == 0 {
+		s.unknown = nil
+	}
+}
+
+// SetUnknownBlock places an unknown block at x, y, z, which is air for everything but saving the column. Loading a
+// section should pass the same UnknownBlock for every block of the same palette entry.
+func (c *ChunkColumn) SetUnknownBlock(x int, y int, z int, b *UnknownBlock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Sections[y/16].setUnknownBlock(x, y, z, b)
+	delete(c.blockEntities, c.blockPos(x, y, z))
+	c.version++
+}
+
+// UnknownBlockAt returns the unknown block at x, y, z in the column, or nil if the block there is known
+func (c *ChunkColumn) UnknownBlockAt(x int, y int, z int) *UnknownBlock {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Sections[y/16].UnknownBlockAt(x, y, z)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+)
+
+type blockReport struct {
+	// Properties are in the order the report lists them, which is also the order the state IDs are numbered in
+	Properties orderedProperties `json:"properties"`
+	States     []struct {
+		Properties map[string]string `json:"properties"`
+		ID         int               `json:"id"`
+		Default    bool              `json:"default"`
+	} `json:"states"`
+}
+
+type property struct {
+	Name   string
+	Values []string
+}
+
+type orderedProperties []property
+
+func (o *orderedProperties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil { // {
+		return err
+	}
+	for dec.More() {
+		name, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var values []string
+		if err := dec.Decode(&values); err != nil {
+			return err
+		}
+		*o = append(*o, property{Name: name.(string), Values: values})
+	}
+	_, err := dec.Token() // }
+	return err
+}
+
+type block struct {
+	Name       string
+	Properties []property
+	MinState   int
+	Default    int
+}
+
+func (b *block) numStates() int {
+	n := 1
+	for _, p := range b.Properties {
+		n *= len(p.Values)
+	}
+	return n
+}
+
+// stateOffset returns how far a state is from the first state of the block. The last property changes the fastest,
+// the same way vanilla numbers them.
+func (b *block) stateOffset(props map[string]string) (int, error) {
+	offset := 0
+	for _, p := range b.Properties {
+		index := -1
+		for i, v := range p.Values {
+			if v == props[p.Name] {
+				index = i
+			}
+		}
+		if index < 0 {
+			return 0, fmt.Errorf("%s: invalid value %q for property %s", b.Name, props[p.Name], p.Name)
+		}
+		offset = offset*len(p.Values) + index
+	}
+	return offset, nil
+}
+
+func readBlocks(path string) ([]block, error) {
+	var report map[string]blockReport
+	if err := readJSON(path, &report); err != nil {
+		return nil, err
+	}
+
+	var all []block
+	for name, r := range report {
+		b := block{Name: name, Properties: r.Properties, MinState: -1, Default: -1}
+		for _, s := range r.States {
+			if b.MinState < 0 || s.ID < b.MinState {
+				b.MinState = s.ID
+			}
+			if s.Default {
+				b.Default = s.ID
+			}
+		}
+		if b.Default < 0 {
+			return nil, fmt.Errorf("%s: no default state", name)
+		}
+		// The registry computes state IDs from the properties instead of storing every state, so make sure that
+		// actually gives the IDs in the report.
+		for _, s := range r.States {
+			offset, err := b.stateOffset(s.Properties)
+			if err != nil {
+				return nil, err
+			}
+			if b.MinState+offset != s.ID {
+				return nil, fmt.Errorf("%s: state %v has ID %d, expected %d", name, s.Properties, s.ID, b.MinState+offset)
+			}
+		}
+		all = append(all, b)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].MinState < all[j].MinState })
+	return all, nil
+}
+
+// partialStates returns the first state ID that is missing from the blocks, or -1 if they number every state from 0 up
+// like the full report does
+func partialStates(all []block) int {
+	next := 0
+	for _, b := range all {
+		if b.MinState != next {
+			return next
+		}
+		next += b.numStates()
+	}
+	return -1
+}
+
+func generateBlocks(path string) ([]byte, error) {
+	all, err := readBlocks(path)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, bl := range all {
+		ids = append(ids, bl.Name)
+	}
+	if err := checkGoNames(ids); err != nil {
+		return nil, err
+	}
+	if missing := partialStates(all); missing >= 0 {
+		log.Printf("%s is not the full report, it has no block with state %d", path, missing)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("package blocks\n\n")
+	b.WriteString("// Default states of every block\nconst (\n")
+	for _, bl := range all {
+		fmt.Fprintf(&b, "%s State = %d\n", goName(bl.Name), bl.Default)
+	}
+	b.WriteString(")\n\n")
+
+	b.WriteString("// registry is sorted by the first state ID of each block\nvar registry = []Block{\n")
+	for _, bl := range all {
+		fmt.Fprintf(&b, "{Name: %q, MinState: %d, DefaultState: %d", bl.Name, bl.MinState, bl.Default)
+		if len(bl.Properties) > 0 {
+			b.WriteString(", Properties: []Property{")
+			for _, p := range bl.Properties {
+				fmt.Fprintf(&b, "{Name: %q, Values: %#v},", p.Name, p.Values)
+			}
+			b.WriteString("}")
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}\n")
+	return writeSource(&b, path)
+}
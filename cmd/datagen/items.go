@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+)
+
+type registriesReport map[string]struct {
+	Entries map[string]struct {
+		ProtocolID int `json:"protocol_id"`
+	} `json:"entries"`
+}
+
+// placedBlocks has the items that place a block with a different name than the item itself
+var placedBlocks = map[string]string{
+	"minecraft:water_bucket":   "minecraft:water",
+	"minecraft:lava_bucket":    "minecraft:lava",
+	"minecraft:redstone":       "minecraft:redstone_wire",
+	"minecraft:string":         "minecraft:tripwire",
+	"minecraft:wheat_seeds":    "minecraft:wheat",
+	"minecraft:beetroot_seeds": "minecraft:beetroots",
+	"minecraft:carrot":         "minecraft:carrots",
+	"minecraft:potato":         "minecraft:potatoes",
+	"minecraft:melon_seeds":    "minecraft:melon_stem",
+	"minecraft:pumpkin_seeds":  "minecraft:pumpkin_stem",
+	"minecraft:sweet_berries":  "minecraft:sweet_berry_bush",
+	"minecraft:cocoa_beans":    "minecraft:cocoa",
+	"minecraft:kelp":           "minecraft:kelp",
+}
+
+type item struct {
+	Name  string
+	ID    int
+	Block string // empty if the item doesn't place a block
+}
+
+func generateItems(registriesPath string, blocksPath string) ([]byte, error) {
+	var report registriesReport
+	if err := readJSON(registriesPath, &report); err != nil {
+		return nil, err
+	}
+	allBlocks, err := readBlocks(blocksPath)
+	if err != nil {
+		return nil, err
+	}
+	blockNames := make(map[string]bool, len(allBlocks))
+	for _, b := range allBlocks {
+		blockNames[b.Name] = true
+	}
+
+	itemRegistry, ok := report["minecraft:item"]
+	if !ok {
+		return nil, fmt.Errorf("%s: no minecraft:item registry", registriesPath)
+	}
+	// Without the block registry, an item whose block is missing from blocks.json would silently place nothing
+	blockRegistry, ok := report["minecraft:block"]
+	if !ok {
+		log.Printf("%s has no minecraft:block registry, the blocks of items aren't checked", registriesPath)
+	}
+	var all []item
+	for name, entry := range itemRegistry.Entries {
+		it := item{Name: name, ID: entry.ProtocolID}
+		block, ok := placedBlocks[name]
+		if !ok && name != "minecraft:air" {
+			block = name
+		}
+		if blockNames[block] {
+			it.Block = block
+		} else if _, ok := blockRegistry.Entries[block]; ok {
+			return nil, fmt.Errorf("%s: block %s of item %s is missing", blocksPath, block, name)
+		}
+		all = append(all, it)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	var ids []string
+	for _, it := range all {
+		ids = append(ids, it.Name)
+	}
+	if err := checkGoNames(ids); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("package items\n\n")
+	b.WriteString("import \"github.com/masp/mcgo/blocks\"\n\n")
+	b.WriteString("const (\n")
+	for _, it := range all {
+		fmt.Fprintf(&b, "%s ID = %d\n", goName(it.Name), it.ID)
+	}
+	b.WriteString(")\n\n")
+
+	b.WriteString("// registry is sorted by item ID\nvar registry = []Item{\n")
+	for _, it := range all {
+		fmt.Fprintf(&b, "{Name: %q, ID: %s", it.Name, goName(it.Name))
+		if it.Block != "" {
+			fmt.Fprintf(&b, ", Block: blocks.%s, PlacesBlock: true", goName(it.Block))
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}\n")
+	return writeSource(&b, registriesPath)
+}
@@ -0,0 +1,97 @@
+// Command datagen generates Go registries from the data reports of the vanilla server, which are created with
+// java -cp server.jar net.minecraft.data.Main --reports
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	blocksReport := flag.String("blocks", "", "path to the blocks.json report")
+	registriesReport := flag.String("registries", "", "path to the registries.json report, generates items if set")
+	out := flag.String("out", "", "path of the generated Go file")
+	flag.Parse()
+
+	var (
+		src []byte
+		err error
+	)
+	switch {
+	case *registriesReport != "" && *blocksReport != "":
+		src, err = generateItems(*registriesReport, *blocksReport)
+	case *blocksReport != "":
+		src, err = generateBlocks(*blocksReport)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeSource formats the generated code so that it looks like it was written by hand
+func writeSource(b *bytes.Buffer, report string) ([]byte, error) {
+	header := fmt.Sprintf("// Code generated by cmd/datagen from %s; DO NOT EDIT.\n\n", report)
+	src, err := format.Source(append([]byte(header), b.Bytes()...))
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// goNames are IDs that get another Go name than goName would give them. minecraft:grass is the short grass (tall grass
+// is minecraft:tall_grass), but blocks.Grass was the grass block before the registries were generated.
+var goNames = map[string]string{
+	"minecraft:grass": "ShortGrass",
+}
+
+// goName turns a namespaced ID like minecraft:oak_stairs into an exported Go name like OakStairs
+func goName(id string) string {
+	if name, ok := goNames[id]; ok {
+		return name
+	}
+	id = strings.TrimPrefix(id, "minecraft:")
+	var name strings.Builder
+	for _, part := range strings.Split(id, "_") {
+		if part == "" {
+			continue
+		}
+		name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return name.String()
+}
+
+// checkGoNames makes sure no two IDs get the same Go name, which would only show up as a compile error in the
+// generated code
+func checkGoNames(ids []string) error {
+	seen := make(map[string]string, len(ids))
+	for _, id := range ids {
+		name := goName(id)
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("%s and %s are both named %s, add one of them to goNames", other, id, name)
+		}
+		seen[name] = id
+	}
+	return nil
+}
@@ -1,38 +1,241 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"github.com/masp/mcgo/access"
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/command"
+	"github.com/masp/mcgo/config"
+	"github.com/masp/mcgo/console"
 	mclog "github.com/masp/mcgo/log"
 	mcnet "github.com/masp/mcgo/net"
-	"github.com/masp/mcgo/pstn"
+	"github.com/masp/mcgo/perms"
+	"github.com/masp/mcgo/rcon"
 	"github.com/masp/mcgo/worlds"
 	log "github.com/sirupsen/logrus"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func init() {
 	// log.SetLevel(log.DebugLevel)
 }
 
+// openLists reads the whitelist, operators and bans from the directory the server runs in, like vanilla
+func openLists(cfg config.Server) (*access.Whitelist, *access.Ops, *access.Bans) {
+	whitelist, err := access.OpenWhitelist(access.WhitelistFile, cfg.WhiteList)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", access.WhitelistFile, err)
+	}
+	ops, err := access.OpenOps(access.OpsFile)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", access.OpsFile, err)
+	}
+	bans, err := access.OpenBans(access.BannedPlayersFile, access.BannedIPsFile)
+	if err != nil {
+		log.Fatalf("failed to read ban lists: %v", err)
+	}
+	return whitelist, ops, bans
+}
+
+const autosaveInterval = 5 * time.Minute
+
+// Players get disconnectTimeout to receive what was queued for them before the server stops, and everything has to be
+// saved within shutdownTimeout
+const (
+	disconnectTimeout = 5 * time.Second
+	shutdownTimeout   = 30 * time.Second
+)
+
+// shutdown kicks every player, saves them and the worlds, and returns the exit code. If that takes longer than
+// shutdownTimeout, exit is called without waiting for the rest.
+func shutdown(server *mcnet.Server, manager *worlds.Manager, exit func(code int)) int {
+	log.Info("Stopping the server")
+	time.AfterFunc(shutdownTimeout, func() {
+		log.Errorf("Saving took longer than %v, stopping anyway", shutdownTimeout)
+		exit(1)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx, chat.Text(mcnet.ShutdownMessage)); err != nil {
+		log.Warnf("Not every player could be disconnected cleanly: %v", err)
+	}
+	log.Info("Saving worlds")
+	if err := manager.Close(); err != nil {
+		log.Errorf("failed to save worlds: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// openWorlds loads the main world with its nether and end, or creates them if they weren't saved before. The nether
+// and end are saved next to the main world with a suffix, like on Bukkit servers, so those can be moved over.
+func openWorlds(cfg config.Server) *worlds.Manager {
+	var manager *worlds.Manager
+	name := "world"
+	if cfg.LevelName != "" {
+		manager = worlds.NewManager(filepath.Dir(cfg.LevelName))
+		name = filepath.Base(cfg.LevelName)
+	} else {
+		manager = worlds.NewManager("")
+	}
+
+	seed := cfg.Seed()
+	gamemode, _ := mcnet.ParseGamemode(cfg.Gamemode)
+	for _, settings := range []worlds.WorldSettings{
+		{Name: name, Type: worlds.Overworld, Generator: cfg.Generator()},
+		{Name: name + "_nether", Type: worlds.Nether},
+		{Name: name + "_the_end", Type: worlds.End},
+	} {
+		settings.Seed = seed
+		settings.GameType = int32(gamemode)
+		if _, err := manager.LoadOrCreate(settings); err != nil {
+			log.Fatalf("failed to open world %s: %v", settings.Name, err)
+		}
+	}
+	return manager
+}
+
+// startRcon listens for RCON clients if that's enabled, otherwise it returns nil
+func startRcon(cfg config.Server, commands *command.Dispatcher) *rcon.Server {
+	if !cfg.EnableRcon {
+		return nil
+	}
+	if cfg.RconPassword == "" {
+		log.Warn("rcon.password isn't set, RCON is disabled")
+		return nil
+	}
+	log.Infof("Opening RCON on %s", cfg.RconAddr())
+	ln, err := net.Listen("tcp", cfg.RconAddr())
+	if err != nil {
+		log.Fatal(err)
+	}
+	remote := rcon.NewServer(cfg.RconPassword, commands)
+	go func() {
+		if err := remote.Serve(ln); err != rcon.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+	return remote
+}
+
 func main() {
+	configPath := flag.String("config", config.DefaultPath, "file the server settings are read from")
+	overrides := config.Flags(flag.CommandLine)
+	flag.Parse()
+
 	mclog.SetupLogging()
-	log.Info("Opening server on port 25565")
+	cons := console.Open()
+	log.SetOutput(cons)
+	log.RegisterExitHandler(func() { _ = cons.Close() })
+	exit := func(code int) {
+		_ = cons.Close()
+		os.Exit(code)
+	}
+	cfg, err := config.Load(*configPath, overrides())
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *configPath, err)
+	}
+	gamemode, _ := mcnet.ParseGamemode(cfg.Gamemode)
+	if cfg.OnlineMode {
+		log.Warn("online-mode isn't supported yet, players won't be authenticated")
+	}
 
-	ln, err := net.Listen("tcp", ":25565")
+	log.Infof("Opening server on %s", cfg.Addr())
+	ln, err := net.Listen("tcp", cfg.Addr())
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer ln.Close()
 
-	log.Info("Generating spawn...")
-	world := worlds.New(pstn.Block{X: 0, Y: 65, Z: 0})
-	log.Info("Finished generating spawn")
-	for {
-		conn, err := ln.Accept()
-		if err != nil {
-			log.Error(err)
-			continue
+	manager := openWorlds(cfg)
+	server := mcnet.NewServer(manager)
+	server.MOTD = chat.FromLegacy(cfg.MOTD)
+	server.MaxPlayers = cfg.MaxPlayers
+	if cfg.ServerIcon != "" {
+		favicon, err := mcnet.LoadFavicon(cfg.ServerIcon)
+		if err == nil {
+			server.Favicon = favicon
+		} else if !os.IsNotExist(err) {
+			log.Errorf("failed to load server icon: %v", err)
 		}
+	}
+	server.ViewDistance = cfg.ViewDistance
+	server.CompressionThreshold = cfg.CompressionThreshold
+	server.Gamemode = gamemode
+	server.PlayerDataDir = cfg.LevelName
+
+	whitelist, ops, bans := openLists(cfg)
+	server.Whitelist = whitelist
+	server.Ops = ops
+	server.Bans = bans
+	permissions, err := perms.Open(perms.File, ops)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", perms.File, err)
+	}
+	server.Permissions = permissions
+	admin := &command.Admin{Server: server, Whitelist: whitelist, Ops: ops, Bans: bans}
+	commands := command.NewDispatcher()
+	if err := admin.Register(commands); err != nil {
+		log.Fatal(err)
+	}
+	server.OnCommand = func(p *mcnet.Player, line string) {
+		_ = commands.Execute(p, line)
+	}
+
+	// Autosaves are scheduled so they stop along with the tick loop, but they run async since saving takes a while
+	overworld := manager.Default()
+	autosaveTicks := worlds.Ticks(autosaveInterval)
+	overworld.Scheduler.Every(autosaveTicks, autosaveTicks, func() {
+		overworld.Scheduler.Async(func() func() {
+			_ = server.SavePlayers()
+			_ = manager.Save()
+			return nil
+		})
+	})
 
-		go mcnet.HandlePlayer(&world, conn)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	var stopping sync.Once
+	var remote *rcon.Server
+	stop := func() {
+		stopping.Do(func() {
+			if remote != nil {
+				remote.Close()
+			}
+			go func() {
+				<-signals
+				log.Warn("Stopping without saving")
+				exit(1)
+			}()
+			exit(shutdown(server, manager, exit))
+		})
 	}
+	cons.OnInterrupt = func() {
+		select {
+		case signals <- os.Interrupt:
+		default:
+		}
+	}
+	for _, cmd := range []*command.Command{command.Stop(stop), command.List(server)} {
+		if err := commands.Register(cmd); err != nil {
+			log.Fatal(err)
+		}
+	}
+	remote = startRcon(cfg, commands)
+	go cons.Run(commands)
+
+	go func() {
+		if err := server.Serve(ln); err != mcnet.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+	<-signals
+	stop()
 }
@@ -0,0 +1,290 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"github.com/masp/mcgo/access"
+	"github.com/masp/mcgo/chat"
+	mcnet "github.com/masp/mcgo/net"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultKickReason is what players are kicked with when no reason is given, like vanilla
+const DefaultKickReason = "Kicked by an operator."
+
+// Admin has the commands that manage who may join the server: whitelist, op, deop, ban, pardon, ban-ip, pardon-ip,
+// banlist and kick. Each needs the permission mc.command.<name>.
+type Admin struct {
+	Server    *mcnet.Server
+	Whitelist *access.Whitelist
+	Ops       *access.Ops
+	Bans      *access.Bans
+}
+
+var _ Sender = (*mcnet.Player)(nil)
+
+// Register adds the commands to d
+func (a *Admin) Register(d *Dispatcher) error {
+	for _, cmd := range []*Command{
+		{Name: "whitelist", Usage: "<add|remove|list|on|off|reload> [player]", Description: "Manages the whitelist", Permission: "mc.command.whitelist", Run: a.whitelist},
+		{Name: "op", Usage: "<player>", Description: "Makes a player operator", Permission: "mc.command.op", Run: a.op},
+		{Name: "deop", Usage: "<player>", Description: "Stops a player from being operator", Permission: "mc.command.deop", Run: a.deop},
+		{Name: "ban", Usage: "<player> [reason]", Description: "Bans a player", Permission: "mc.command.ban", Run: a.ban},
+		{Name: "pardon", Usage: "<player>", Description: "Lifts the ban of a player", Permission: "mc.command.pardon", Run: a.pardon},
+		{Name: "ban-ip", Usage: "<address|player> [reason]", Description: "Bans an address", Permission: "mc.command.ban-ip", Run: a.banIP},
+		{Name: "pardon-ip", Usage: "<address>", Description: "Lifts the ban of an address", Permission: "mc.command.pardon-ip", Run: a.pardonIP},
+		{Name: "banlist", Usage: "[players|ips]", Description: "Lists the bans", Permission: "mc.command.banlist", Run: a.banlist},
+		{Name: "kick", Usage: "<player> [reason]", Description: "Disconnects a player", Permission: "mc.command.kick", Run: a.kick},
+	} {
+		if err := d.Register(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// online returns the player with the given name that is online
+func (a *Admin) online(name string) *mcnet.Player {
+	for _, p := range a.Server.Players() {
+		if strings.EqualFold(p.Username, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// profile returns the player with the given name. Players that aren't online get the UUID they would have when
+// they join, since players aren't authenticated.
+func (a *Admin) profile(name string) access.Profile {
+	if p := a.online(name); p != nil {
+		return access.Profile{UUID: p.UUID, Name: p.Username}
+	}
+	return access.Profile{UUID: mcnet.OfflineUUID(name), Name: name}
+}
+
+func (a *Admin) whitelist(ctx *Context) error {
+	if len(ctx.Args) == 0 {
+		return ErrUsage
+	}
+	switch strings.ToLower(ctx.Args[0]) {
+	case "add", "remove":
+		if len(ctx.Args) != 2 {
+			return ErrUsage
+		}
+		profile := a.profile(ctx.Args[1])
+		if strings.ToLower(ctx.Args[0]) == "add" {
+			added, err := a.Whitelist.Add(profile)
+			if err != nil {
+				return err
+			} else if !added {
+				return errors.New("Player is already whitelisted")
+			}
+			ctx.Reply("Added %s to the whitelist", profile.Name)
+			return nil
+		}
+		removed, err := a.Whitelist.Remove(profile.UUID)
+		if err != nil {
+			return err
+		} else if !removed {
+			return errors.New("Player is not whitelisted")
+		}
+		ctx.Reply("Removed %s from the whitelist", profile.Name)
+	case "list":
+		players := a.Whitelist.Players()
+		if len(players) == 0 {
+			ctx.Reply("There are no whitelisted players")
+			return nil
+		}
+		names := make([]string, len(players))
+		for i, p := range players {
+			names[i] = p.Name
+		}
+		ctx.Reply("There are %d whitelisted players: %s", len(names), strings.Join(names, ", "))
+	case "on", "off":
+		enabled := strings.ToLower(ctx.Args[0]) == "on"
+		if a.Whitelist.Enabled() == enabled {
+			return fmt.Errorf("Whitelist is already turned %s", ctx.Args[0])
+		}
+		a.Whitelist.SetEnabled(enabled)
+		ctx.Reply("Whitelist is now turned %s", ctx.Args[0])
+	case "reload":
+		if err := a.Whitelist.Reload(); err != nil {
+			return err
+		}
+		ctx.Reply("Reloaded the whitelist")
+	default:
+		return ErrUsage
+	}
+	return nil
+}
+
+func (a *Admin) op(ctx *Context) error {
+	if len(ctx.Args) != 1 {
+		return ErrUsage
+	}
+	profile := a.profile(ctx.Args[0])
+	if a.Ops.Level(profile.UUID) > 0 {
+		return errors.New("Nothing changed. The player already is an operator")
+	}
+	if err := a.Ops.Add(access.Op{Profile: profile, Level: access.DefaultOpLevel}); err != nil {
+		return err
+	}
+	ctx.Reply("Made %s a server operator", profile.Name)
+	return nil
+}
+
+func (a *Admin) deop(ctx *Context) error {
+	if len(ctx.Args) != 1 {
+		return ErrUsage
+	}
+	profile := a.profile(ctx.Args[0])
+	removed, err := a.Ops.Remove(profile.UUID)
+	if err != nil {
+		return err
+	} else if !removed {
+		return errors.New("Nothing changed. The player is not an operator")
+	}
+	ctx.Reply("Made %s no longer a server operator", profile.Name)
+	return nil
+}
+
+func (a *Admin) ban(ctx *Context) error {
+	if len(ctx.Args) == 0 {
+		return ErrUsage
+	}
+	profile := a.profile(ctx.Args[0])
+	if _, banned := a.Bans.PlayerBan(profile.UUID); banned {
+		return errors.New("Nothing changed. The player is already banned")
+	}
+	entry := access.NewBan(ctx.Sender.Name(), ctx.Rest(1), time.Time{})
+	if err := a.Bans.BanPlayer(profile, entry); err != nil {
+		return err
+	}
+	ctx.Reply("Banned %s: %s", profile.Name, entry.Reason)
+	if p := a.online(profile.Name); p != nil {
+		p.Kick(mcnet.PlayerBannedMessage(mcnet.Ban{Reason: entry.Reason}))
+	}
+	return nil
+}
+
+func (a *Admin) pardon(ctx *Context) error {
+	if len(ctx.Args) != 1 {
+		return ErrUsage
+	}
+	profile := a.profile(ctx.Args[0])
+	pardoned, err := a.Bans.PardonPlayer(profile.UUID)
+	if err != nil {
+		return err
+	} else if !pardoned {
+		return errors.New("Nothing changed. The player isn't banned")
+	}
+	ctx.Reply("Unbanned %s", profile.Name)
+	return nil
+}
+
+func (a *Admin) banIP(ctx *Context) error {
+	if len(ctx.Args) == 0 {
+		return ErrUsage
+	}
+	ip := net.ParseIP(ctx.Args[0])
+	if ip == nil {
+		p := a.online(ctx.Args[0])
+		if p == nil || p.IP() == nil {
+			return errors.New("Invalid IP address or unknown player")
+		}
+		ip = p.IP()
+	}
+	if _, banned := a.Bans.IPBan(ip); banned {
+		return errors.New("Nothing changed. That IP is already banned")
+	}
+	entry := access.NewBan(ctx.Sender.Name(), ctx.Rest(1), time.Time{})
+	if err := a.Bans.BanIP(ip, entry); err != nil {
+		return err
+	}
+
+	var kicked []*mcnet.Player
+	for _, p := range a.Server.Players() {
+		if ip.Equal(p.IP()) {
+			kicked = append(kicked, p)
+		}
+	}
+	ctx.Reply("Banned IP %s: %s", ip, entry.Reason)
+	if len(kicked) > 0 {
+		names := make([]string, len(kicked))
+		for i, p := range kicked {
+			names[i] = p.Username
+			p.Kick(mcnet.IPBannedMessage(mcnet.Ban{Reason: entry.Reason}))
+		}
+		ctx.Reply("This ban affects %d player(s): %s", len(kicked), strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func (a *Admin) pardonIP(ctx *Context) error {
+	if len(ctx.Args) != 1 {
+		return ErrUsage
+	}
+	ip := net.ParseIP(ctx.Args[0])
+	if ip == nil {
+		return errors.New("Invalid IP address")
+	}
+	pardoned, err := a.Bans.PardonIP(ip)
+	if err != nil {
+		return err
+	} else if !pardoned {
+		return errors.New("Nothing changed. That IP isn't banned")
+	}
+	ctx.Reply("Unbanned IP %s", ip)
+	return nil
+}
+
+func (a *Admin) banlist(ctx *Context) error {
+	var lines []string
+	kind := "both"
+	if len(ctx.Args) > 0 {
+		kind = strings.ToLower(ctx.Args[0])
+	}
+	switch kind {
+	case "both", "players", "ips":
+	default:
+		return ErrUsage
+	}
+	if kind != "ips" {
+		for _, b := range a.Bans.Players() {
+			lines = append(lines, fmt.Sprintf("%s was banned by %s: %s", b.Name, b.Source, b.Reason))
+		}
+	}
+	if kind != "players" {
+		for _, b := range a.Bans.IPs() {
+			lines = append(lines, fmt.Sprintf("%s was banned by %s: %s", b.IP, b.Source, b.Reason))
+		}
+	}
+	if len(lines) == 0 {
+		ctx.Reply("There are no bans")
+		return nil
+	}
+	ctx.Reply("There are %d ban(s):", len(lines))
+	for _, line := range lines {
+		ctx.Reply("%s", line)
+	}
+	return nil
+}
+
+func (a *Admin) kick(ctx *Context) error {
+	if len(ctx.Args) == 0 {
+		return ErrUsage
+	}
+	p := a.online(ctx.Args[0])
+	if p == nil {
+		return errors.New("No player was found")
+	}
+	reason := ctx.Rest(1)
+	if reason == "" {
+		reason = DefaultKickReason
+	}
+	ctx.Reply("Kicked %s: %s", p.Username, reason)
+	p.Kick(chat.Text(reason))
+	return nil
+}
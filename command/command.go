@@ -0,0 +1,141 @@
+// Package command parses and runs the commands that players type in chat, like /ban or /whitelist.
+package command
+
+import (
+	"errors"
+	"fmt"
+	"github.com/masp/mcgo/chat"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrNoPermission   = errors.New("no permission")
+	// ErrUsage is returned by commands that were given the wrong arguments, the sender is shown how to use the command
+	ErrUsage = errors.New("invalid usage")
+)
+
+// Sender is whoever runs a command
+type Sender interface {
+	Name() string
+	SendMessage(msg chat.Component)
+	HasPermission(node string) bool
+}
+
+// Command is a command that can be run as /<name> or /<alias>
+type Command struct {
+	Name    string
+	Aliases []string
+	// Usage are the arguments of the command, like "<player> [reason]"
+	Usage       string
+	Description string
+	// Permission is the node that is needed to run the command, everyone may run it if it's empty
+	Permission string
+	// Run runs the command. The message of the error it returns is shown to the sender.
+	Run func(ctx *Context) error
+}
+
+// Context is a command that is being run
+type Context struct {
+	Sender  Sender
+	Command *Command
+	Label   string // the name or alias the command was run with
+	Args    []string
+}
+
+// Reply sends a message to the sender of the command
+func (ctx *Context) Reply(format string, args ...interface{}) {
+	ctx.Sender.SendMessage(chat.Text(fmt.Sprintf(format, args...)))
+}
+
+// Rest returns the arguments from i on as one string, for arguments like reasons that may contain spaces
+func (ctx *Context) Rest(i int) string {
+	if i >= len(ctx.Args) {
+		return ""
+	}
+	return strings.Join(ctx.Args[i:], " ")
+}
+
+// Dispatcher finds the command that is run and runs it
+type Dispatcher struct {
+	mu       sync.RWMutex
+	commands map[string]*Command // by name and alias
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{commands: make(map[string]*Command)}
+}
+
+// Register adds a command. It fails if its name or one of its aliases is already taken.
+func (d *Dispatcher) Register(cmd *Command) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	labels := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, label := range labels {
+		if _, ok := d.commands[strings.ToLower(label)]; ok {
+			return fmt.Errorf("command /%s is already registered", label)
+		}
+	}
+	for _, label := range labels {
+		d.commands[strings.ToLower(label)] = cmd
+	}
+	return nil
+}
+
+// Lookup returns the command with the given name or alias
+func (d *Dispatcher) Lookup(label string) (*Command, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	cmd, ok := d.commands[strings.ToLower(label)]
+	return cmd, ok
+}
+
+// Commands returns every command sorted by name
+func (d *Dispatcher) Commands() []*Command {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var commands []*Command
+	for label, cmd := range d.commands {
+		if label == strings.ToLower(cmd.Name) {
+			commands = append(commands, cmd)
+		}
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// Allowed returns true if the sender may run the command
+func Allowed(sender Sender, cmd *Command) bool {
+	return cmd.Permission == "" || sender.HasPermission(cmd.Permission)
+}
+
+// Execute runs a command line without the leading slash. Errors are shown to the sender, and returned so whoever
+// executed the line can tell if it worked.
+func (d *Dispatcher) Execute(sender Sender, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ErrUnknownCommand
+	}
+	cmd, ok := d.Lookup(fields[0])
+	if !ok || !Allowed(sender, cmd) {
+		// Like vanilla, commands the sender can't run look like they don't exist
+		sender.SendMessage(chat.Colored("Unknown command", "red"))
+		if !ok {
+			return ErrUnknownCommand
+		}
+		return ErrNoPermission
+	}
+
+	log.Infof("%s issued server command: /%s", sender.Name(), line)
+	ctx := &Context{Sender: sender, Command: cmd, Label: fields[0], Args: fields[1:]}
+	err := cmd.Run(ctx)
+	if errors.Is(err, ErrUsage) {
+		sender.SendMessage(chat.Colored("Usage: /"+ctx.Label+" "+cmd.Usage, "red"))
+	} else if err != nil {
+		sender.SendMessage(chat.Colored(err.Error(), "red"))
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"errors"
+	"github.com/masp/mcgo/chat"
+	"testing"
+)
+
+type testSender struct {
+	permissions []string
+	messages    []string
+}
+
+func (s *testSender) Name() string { return "tester" }
+
+func (s *testSender) SendMessage(msg chat.Component) {
+	s.messages = append(s.messages, msg.PlainText())
+}
+
+func (s *testSender) HasPermission(node string) bool {
+	for _, p := range s.permissions {
+		if p == node {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDispatcher_Execute(t *testing.T) {
+	d := NewDispatcher()
+	var args []string
+	echo := &Command{Name: "echo", Aliases: []string{"say"}, Usage: "<message>", Permission: "test.echo", Run: func(ctx *Context) error {
+		if len(ctx.Args) == 0 {
+			return ErrUsage
+		}
+		args = ctx.Args
+		ctx.Reply("%s", ctx.Rest(0))
+		return nil
+	}}
+	if err := d.Register(echo); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := d.Register(&Command{Name: "say"}); err == nil {
+		t.Errorf("Register() of a taken alias succeeded")
+	}
+
+	op := &testSender{permissions: []string{"test.echo"}}
+	if err := d.Execute(op, "SAY hello   world"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(args) != 2 || op.messages[0] != "hello world" {
+		t.Errorf("ran with %q and replied %q", args, op.messages)
+	}
+
+	if err := d.Execute(op, "echo"); !errors.Is(err, ErrUsage) {
+		t.Errorf("Execute() without arguments = %v, want ErrUsage", err)
+	}
+	if got := op.messages[len(op.messages)-1]; got != "Usage: /echo <message>" {
+		t.Errorf("usage message = %q", got)
+	}
+	if err := d.Execute(op, "missing"); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Execute() of an unknown command = %v, want ErrUnknownCommand", err)
+	}
+	if err := d.Execute(&testSender{}, "echo hi"); !errors.Is(err, ErrNoPermission) {
+		t.Errorf("Execute() without permission = %v, want ErrNoPermission", err)
+	}
+	if cmds := d.Commands(); len(cmds) != 1 || cmds[0] != echo {
+		t.Errorf("Commands() = %v, want only echo", cmds)
+	}
+}
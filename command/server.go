@@ -0,0 +1,39 @@
+package command
+
+import (
+	mcnet "github.com/masp/mcgo/net"
+	"strings"
+)
+
+// Stop returns the stop command, which shuts the server down by calling stop on its own goroutine
+func Stop(stop func()) *Command {
+	return &Command{
+		Name:        "stop",
+		Description: "Stops the server",
+		Permission:  "mc.command.stop",
+		Run: func(ctx *Context) error {
+			ctx.Reply("Stopping the server")
+			go stop()
+			return nil
+		},
+	}
+}
+
+// List returns the list command, which shows who is online
+func List(server *mcnet.Server) *Command {
+	return &Command{
+		Name:        "list",
+		Description: "Lists the players that are online",
+		// Everyone may list the players, like in vanilla, also with permission files from before the command existed
+		Run: func(ctx *Context) error {
+			players := server.Players()
+			names := make([]string, len(players))
+			for i, p := range players {
+				names[i] = p.Username
+			}
+			ctx.Reply("There are %d of a max of %d players online: %s", len(players), server.MaxPlayers,
+				strings.Join(names, ", "))
+			return nil
+		},
+	}
+}
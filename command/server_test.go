@@ -0,0 +1,21 @@
+package command
+
+import (
+	mcnet "github.com/masp/mcgo/net"
+	"github.com/masp/mcgo/worlds"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	d := NewDispatcher()
+	if err := d.Register(List(mcnet.NewServer(worlds.NewManager("")))); err != nil {
+		t.Fatal(err)
+	}
+	sender := &testSender{} // no permissions, like players in permission files from before /list
+	if err := d.Execute(sender, "list"); err != nil {
+		t.Fatalf("Execute(list) error = %v", err)
+	}
+	if want := "There are 0 of a max of 20 players online: "; len(sender.messages) != 1 || sender.messages[0] != want {
+		t.Errorf("list replied %q, want %q", sender.messages, want)
+	}
+}
@@ -0,0 +1,309 @@
+// Package config reads the settings of the server from a server.properties file that vanilla servers can also read,
+// with flags on the command line to override any of them.
+package config
+
+import (
+	"flag"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// DefaultPath is where the server looks for its settings when no other file is given
+const DefaultPath = "server.properties"
+
+// Gamemodes are the names gamemode can be set to
+var Gamemodes = []string{"survival", "creative", "adventure", "spectator"}
+
+// Server are the settings of the server
+type Server struct {
+	ServerIP   string // address to listen on, every address if empty
+	ServerPort int
+	MOTD       string // shown below the name of the server in the server list, with § formatting codes
+	MaxPlayers int
+	// WhiteList only lets the players in whitelist.json join
+	WhiteList bool
+	// EnableRcon listens for RCON clients on RconPort of ServerIP, which may run any command with RconPassword. RCON
+	// stays disabled if the password is empty.
+	EnableRcon   bool
+	RconPort     int
+	RconPassword string
+	// ServerIcon is a 64x64 PNG file shown next to the server in the server list, if it exists
+	ServerIcon string
+	// ViewDistance is how many chunks around them players see in each direction
+	ViewDistance int
+	// OnlineMode means players are authenticated with Mojang, which isn't supported yet
+	OnlineMode bool
+	// CompressionThreshold is the size from which packets are compressed, compression is disabled if it's negative
+	CompressionThreshold int
+	// LevelName is the directory of the main world. Its nether and end are saved next to it. If it's empty, the worlds
+	// are only kept in memory.
+	LevelName string
+	// LevelSeed is the seed of new worlds, a number or any text. A random seed is used if it's empty.
+	LevelSeed string
+	// LevelType is "flat" for a flat world of the preset in GeneratorSettings, or any other vanilla level type (like
+	// "default" or "amplified") for natural terrain. It's kept the way it was written, see levelTypes.
+	LevelType         string
+	GeneratorSettings string
+	Gamemode          string
+}
+
+// Default returns the settings of a new server
+func Default() Server {
+	return Server{
+		ServerPort:           25565,
+		MOTD:                 "A Minecraft Server",
+		MaxPlayers:           20,
+		RconPort:             25575,
+		ServerIcon:           "server-icon.png",
+		ViewDistance:         10,
+		CompressionThreshold: 256,
+		LevelName:            "world",
+		LevelType:            "flat",
+		Gamemode:             "creative",
+	}
+}
+
+// setting is how a field of Server is stored in server.properties
+type setting struct {
+	key   string
+	usage string
+	get   func(s *Server) string
+	set   func(s *Server, value string) error
+}
+
+var settings = []setting{
+	stringSetting("server-ip", "address to listen on, every address if empty", func(s *Server) *string { return &s.ServerIP }),
+	intSetting("server-port", "port to listen on", 1, 65535, func(s *Server) *int { return &s.ServerPort }),
+	stringSetting("motd", "message shown in the server list", func(s *Server) *string { return &s.MOTD }),
+	intSetting("max-players", "most players that can be online at the same time", 0, 1<<31-1, func(s *Server) *int { return &s.MaxPlayers }),
+	boolSetting("enable-rcon", "let RCON clients run commands with rcon.password", func(s *Server) *bool { return &s.EnableRcon }),
+	intSetting("rcon.port", "port to listen for RCON clients on", 1, 65535, func(s *Server) *int { return &s.RconPort }),
+	stringSetting("rcon.password", "password of RCON clients, RCON is disabled if it's empty", func(s *Server) *string { return &s.RconPassword }),
+	boolSetting("white-list", "only let the players in whitelist.json join", func(s *Server) *bool { return &s.WhiteList }),
+	stringSetting("server-icon", "64x64 PNG file shown in the server list", func(s *Server) *string { return &s.ServerIcon }),
+	intSetting("view-distance", "how many chunks around them players see", 2, 32, func(s *Server) *int { return &s.ViewDistance }),
+	boolSetting("online-mode", "authenticate players with Mojang (not supported yet)", func(s *Server) *bool { return &s.OnlineMode }),
+	intSetting("network-compression-threshold", "compress packets from this size on, -1 to disable compression", -1, 1<<31-1, func(s *Server) *int { return &s.CompressionThreshold }),
+	stringSetting("level-name", "directory of the main world, empty to keep the worlds in memory", func(s *Server) *string { return &s.LevelName }),
+	stringSetting("level-seed", "seed of new worlds, random if empty", func(s *Server) *string { return &s.LevelSeed }),
+	levelTypeSetting("level-type", "generator of new worlds: flat, or default for terrain", func(s *Server) *string { return &s.LevelType }),
+	stringSetting("generator-settings", "superflat preset of flat worlds", func(s *Server) *string { return &s.GeneratorSettings }),
+	choiceSetting("gamemode", "gamemode of players: survival, creative, adventure or spectator", Gamemodes, func(s *Server) *string { return &s.Gamemode }),
+}
+
+func stringSetting(key string, usage string, field func(s *Server) *string) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		get:   func(s *Server) string { return *field(s) },
+		set: func(s *Server, value string) error {
+			*field(s) = value
+			return nil
+		},
+	}
+}
+
+func choiceSetting(key string, usage string, choices []string, field func(s *Server) *string) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		get:   func(s *Server) string { return *field(s) },
+		set: func(s *Server, value string) error {
+			for _, choice := range choices {
+				if value == choice {
+					*field(s) = value
+					return nil
+				}
+			}
+			return fmt.Errorf("must be one of %v", choices)
+		},
+	}
+}
+
+// levelTypes maps the level types of vanilla, in lower case, to the generator that comes closest to them
+var levelTypes = map[string]string{
+	"flat":        "flat",
+	"default":     "terrain",
+	"largebiomes": "terrain",
+	"amplified":   "terrain",
+	"default_1_1": "terrain",
+	"buffet":      "terrain",
+}
+
+// levelTypeSetting accepts every level type vanilla does in any case, since server.properties of vanilla servers can
+// have any of them. Types that aren't known fall back to terrain with a warning instead of keeping the server from
+// starting.
+func levelTypeSetting(key string, usage string, field func(s *Server) *string) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		get:   func(s *Server) string { return *field(s) },
+		set: func(s *Server, value string) error {
+			if _, ok := levelTypes[strings.ToLower(value)]; !ok {
+				log.Warnf("config: unknown %s %q, generating terrain instead", key, value)
+			}
+			*field(s) = value
+			return nil
+		},
+	}
+}
+
+func intSetting(key string, usage string, min int, max int, field func(s *Server) *int) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		get:   func(s *Server) string { return strconv.Itoa(*field(s)) },
+		set: func(s *Server, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("not a number")
+			}
+			if n < min || n > max {
+				return fmt.Errorf("must be between %d and %d", min, max)
+			}
+			*field(s) = n
+			return nil
+		},
+	}
+}
+
+func boolSetting(key string, usage string, field func(s *Server) *bool) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		get:   func(s *Server) string { return strconv.FormatBool(*field(s)) },
+		set: func(s *Server, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("must be true or false")
+			}
+			*field(s) = b
+			return nil
+		},
+	}
+}
+
+// Parse reads the settings from properties. Settings that aren't in them keep their default, and properties that
+// aren't settings of this server are ignored.
+func Parse(props Properties) (Server, error) {
+	s := Default()
+	for _, setting := range settings {
+		value, ok := props[setting.key]
+		if !ok {
+			continue
+		}
+		if err := setting.set(&s, value); err != nil {
+			return Server{}, fmt.Errorf("invalid %s %q: %w", setting.key, value, err)
+		}
+	}
+	return s, nil
+}
+
+// Properties returns the settings as they are stored in server.properties
+func (s Server) Properties() Properties {
+	props := make(Properties, len(settings))
+	for _, setting := range settings {
+		props[setting.key] = setting.get(&s)
+	}
+	return props
+}
+
+// Flags adds a flag for every setting to fs, named like the key in server.properties. The returned function returns
+// the flags that were set once fs is parsed.
+func Flags(fs *flag.FlagSet) func() Properties {
+	for _, setting := range settings {
+		fs.String(setting.key, "", setting.usage+" (overrides "+DefaultPath+")")
+	}
+	return func() Properties {
+		props := make(Properties)
+		fs.Visit(func(f *flag.Flag) {
+			for _, setting := range settings {
+				if f.Name == setting.key {
+					props[f.Name] = f.Value.String()
+				}
+			}
+		})
+		return props
+	}
+}
+
+// Load reads the settings from the properties file at path and applies the overrides on top. If there is no file,
+// one with the default settings is written so they can be changed there.
+func Load(path string, overrides Properties) (Server, error) {
+	props, err := readFile(path)
+	if os.IsNotExist(err) {
+		props = Default().Properties()
+		err = writeFile(path, props)
+	}
+	if err != nil {
+		return Server{}, err
+	}
+	for key, value := range overrides {
+		props[key] = value
+	}
+	return Parse(props)
+}
+
+func readFile(path string) (Properties, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadProperties(f)
+}
+
+func writeFile(path string, props Properties) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := props.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Addr returns the address to listen on
+func (s Server) Addr() string {
+	return net.JoinHostPort(s.ServerIP, strconv.Itoa(s.ServerPort))
+}
+
+// RconAddr returns the address to listen for RCON clients on
+func (s Server) RconAddr() string {
+	return net.JoinHostPort(s.ServerIP, strconv.Itoa(s.RconPort))
+}
+
+// Seed returns the seed for new worlds. Like vanilla, a seed that isn't a number is hashed like Java hashes strings.
+func (s Server) Seed() int64 {
+	if s.LevelSeed == "" {
+		return rand.New(rand.NewSource(time.Now().UnixNano())).Int63()
+	}
+	if n, err := strconv.ParseInt(s.LevelSeed, 10, 64); err == nil && n != 0 {
+		return n
+	}
+	var hash int32
+	for _, c := range utf16.Encode([]rune(s.LevelSeed)) {
+		hash = 31*hash + int32(c)
+	}
+	return int64(hash)
+}
+
+// Generator returns the description of the generator of new worlds, as understood by worlds.ParseGenerator
+func (s Server) Generator() string {
+	if levelTypes[strings.ToLower(s.LevelType)] != "flat" {
+		return "terrain"
+	}
+	if s.GeneratorSettings == "" {
+		return ""
+	}
+	return "flat:" + s.GeneratorSettings
+}
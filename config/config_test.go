@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadProperties(t *testing.T) {
+	file := `#Minecraft server properties
+#Mon Oct 19 12:00:00 UTC 2026
+motd=A §aColorful§r Server
+generator-settings=minecraft\:bedrock,2*minecraft\:dirt
+  server-port = 25566
+level-name:worlds/main
+online-mode false
+! another comment
+empty=
+`
+	got, err := ReadProperties(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("ReadProperties() error = %v", err)
+	}
+	want := Properties{
+		"motd":               "A §aColorful§r Server",
+		"generator-settings": "minecraft:bedrock,2*minecraft:dirt",
+		"server-port":        "25566",
+		"level-name":         "worlds/main",
+		"online-mode":        "false",
+		"empty":              "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadProperties() = %v, want %v", got, want)
+	}
+}
+
+func TestProperties_WriteTo(t *testing.T) {
+	props := Properties{
+		"motd":       " Welcome to 🌍 = fun:#1\n",
+		"key with =": "value",
+	}
+	var b bytes.Buffer
+	if _, err := props.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if want := `motd=\ Welcome to \uD83C\uDF0D \= fun\:\#1\n`; !strings.Contains(b.String(), want) {
+		t.Errorf("WriteTo() wrote\n%s\nwant it to contain %s", b.String(), want)
+	}
+	read, err := ReadProperties(&b)
+	if err != nil {
+		t.Fatalf("ReadProperties() error = %v", err)
+	}
+	if !reflect.DeepEqual(read, props) {
+		t.Errorf("read back %v, want %v", read, props)
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse(Properties{"server-port": "25566", "gamemode": "survival", "online-mode": "true", "unknown": "x"})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := Default()
+	want.ServerPort = 25566
+	want.Gamemode = "survival"
+	want.OnlineMode = true
+	if s != want {
+		t.Errorf("Parse() = %+v, want %+v", s, want)
+	}
+
+	for _, invalid := range []Properties{
+		{"server-port": "70000"},
+		{"rcon.port": "0"},
+		{"max-players": "many"},
+		{"online-mode": "maybe"},
+		{"gamemode": "hardcore"},
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("Parse(%v) error = nil, want error", invalid)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, DefaultPath)
+
+	fs := flag.NewFlagSet("mc", flag.ContinueOnError)
+	overrides := Flags(fs)
+	if err := fs.Parse([]string{"-motd", "From the command line", "-view-distance", "4"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path, overrides())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.MOTD != "From the command line" || s.ViewDistance != 4 || s.ServerPort != 25565 {
+		t.Errorf("Load() = %+v, want the defaults with the flags applied", s)
+	}
+
+	written, err := readFile(path)
+	if err != nil {
+		t.Fatalf("default settings weren't written: %v", err)
+	}
+	if !reflect.DeepEqual(written, Default().Properties()) {
+		t.Errorf("wrote %v, want the defaults without the flags", written)
+	}
+}
+
+func TestServer_Seed(t *testing.T) {
+	tests := []struct {
+		seed string
+		want int64
+	}{
+		{"12345", 12345},
+		{"-9876543210", -9876543210},
+		{"hello", 99162322}, // "hello".hashCode() in Java
+		{"0", 48},
+	}
+	for _, tt := range tests {
+		if got := (Server{LevelSeed: tt.seed}).Seed(); got != tt.want {
+			t.Errorf("Seed() of %q = %d, want %d", tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestServer_Generator(t *testing.T) {
+	tests := []struct {
+		levelType string
+		settings  string
+		want      string
+	}{
+		{"flat", "", ""},
+		{"FLAT", "minecraft:bedrock;minecraft:plains", "flat:minecraft:bedrock;minecraft:plains"},
+		{"default", "", "terrain"},
+		{"DEFAULT", "", "terrain"},
+		{"largeBiomes", "", "terrain"},
+		{"amplified", "", "terrain"},
+		{"moon", "", "terrain"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(Properties{"level-type": tt.levelType, "generator-settings": tt.settings})
+		if err != nil {
+			t.Errorf("Parse(level-type=%s) error = %v", tt.levelType, err)
+			continue
+		}
+		if got := s.Generator(); got != tt.want {
+			t.Errorf("Generator() of level-type=%s = %q, want %q", tt.levelType, got, tt.want)
+		}
+		if s.LevelType != tt.levelType {
+			t.Errorf("LevelType = %q, want it kept as %q", s.LevelType, tt.levelType)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Properties are the keys and values of a Java properties file like server.properties
+type Properties map[string]string
+
+// ReadProperties parses a properties file. Lines starting with # or ! are comments, and keys are separated from their
+// values by = or :. Backslash escapes are unescaped, lines continued with a trailing backslash aren't supported.
+func ReadProperties(r io.Reader) (Properties, error) {
+	props := make(Properties)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimLeft(scanner.Text(), " \t\f")
+		if text == "" || text[0] == '#' || text[0] == '!' {
+			continue
+		}
+		key, value := splitProperty(text)
+		key, err := unescape(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		value, err = unescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		props[key] = value
+	}
+	return props, scanner.Err()
+}
+
+// splitProperty splits a line at the first unescaped = or :, or whitespace if there is neither
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return strings.TrimRight(line[:i], " \t\f"), strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			rest := strings.TrimLeft(line[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = strings.TrimLeft(rest[1:], " \t\f")
+			}
+			return line[:i], rest
+		}
+	}
+	return line, ""
+}
+
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(s) {
+				return "", fmt.Errorf("invalid escape %q", s[i-1:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape %q", s[i-1:i+5])
+			}
+			i += 4
+			// Java escapes characters outside the BMP as two UTF-16 surrogates
+			if utf16.IsSurrogate(rune(r)) && i+6 < len(s) && s[i+1:i+3] == "\\u" {
+				if low, err := strconv.ParseUint(s[i+3:i+7], 16, 16); err == nil {
+					b.WriteRune(utf16.DecodeRune(rune(r), rune(low)))
+					i += 6
+					continue
+				}
+			}
+			b.WriteRune(rune(r))
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// WriteTo writes the properties sorted by key, like vanilla writes server.properties
+func (p Properties) WriteTo(w io.Writer) (int64, error) {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("#Minecraft server properties\n")
+	for _, key := range keys {
+		b.WriteString(escape(key, true))
+		b.WriteByte('=')
+		b.WriteString(escape(p[key], false))
+		b.WriteByte('\n')
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func escape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\', '=', ':', '#', '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\t':
+			b.WriteString("\\t")
+		case '\n':
+			b.WriteString("\\n")
+		case '\r':
+			b.WriteString("\\r")
+		case '\f':
+			b.WriteString("\\f")
+		case ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		default:
+			if r < 0x20 || r > 0x7e {
+				// Vanilla reads server.properties as ISO 8859-1, so everything else is escaped
+				if r1, r2 := utf16.EncodeRune(r); r1 != unicode.ReplacementChar {
+					fmt.Fprintf(&b, "\\u%04X\\u%04X", r1, r2)
+				} else {
+					fmt.Fprintf(&b, "\\u%04X", r)
+				}
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
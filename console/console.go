@@ -0,0 +1,325 @@
+// Package console is the interactive console of the server. Commands typed into it are run like the commands of
+// players, and log output is printed above the line that is being typed so it doesn't get mixed into it.
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/command"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Prompt is shown in front of the line that is being typed
+const Prompt = "> "
+
+// maxHistory is how many lines are remembered for the up and down keys
+const maxHistory = 100
+
+// Name is how the console appears as the sender of commands, like vanilla
+const Name = "Server"
+
+// Console reads commands from a terminal. Set it as the output of the log, so log lines are printed above the line
+// that is being typed.
+type Console struct {
+	in      *bufio.Reader
+	out     io.Writer
+	editing bool         // the console echoes and edits the line itself, otherwise the terminal does
+	restore func() error // puts the terminal back how it was, nil if it wasn't changed
+
+	// OnInterrupt is called when Ctrl+C is pressed while editing, since the terminal doesn't send a signal then
+	OnInterrupt func()
+
+	mu      sync.Mutex // guards out and the line
+	line    []rune
+	cursor  int
+	history []string
+}
+
+// Open returns a console on stdin and stdout. If stdin is a terminal the console edits lines itself, which gives it
+// history, otherwise it reads lines as they come.
+func Open() *Console {
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	c := New(os.Stdin, os.Stdout, err == nil)
+	c.restore = restore
+	if c.editing {
+		c.redraw()
+	}
+	return c
+}
+
+// New returns a console that reads from in and writes to out. If editing is set, in is read key by key like a
+// terminal in raw mode.
+func New(in io.Reader, out io.Writer, editing bool) *Console {
+	return &Console{in: bufio.NewReader(in), out: out, editing: editing}
+}
+
+// Close restores the terminal
+func (c *Console) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.editing {
+		fmt.Fprint(c.out, "\r\x1b[K")
+	}
+	if c.restore == nil {
+		return nil
+	}
+	err := c.restore()
+	c.restore = nil
+	return err
+}
+
+// Write prints output above the line that is being typed
+func (c *Console) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.editing {
+		return c.out.Write(p)
+	}
+	fmt.Fprint(c.out, "\r\x1b[K")
+	n, err := c.out.Write(p)
+	c.draw()
+	return n, err
+}
+
+// redraw shows the line that is being typed again
+func (c *Console) redraw() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprint(c.out, "\r\x1b[K")
+	c.draw()
+}
+
+func (c *Console) draw() {
+	fmt.Fprint(c.out, Prompt, string(c.line))
+	if back := len(c.line) - c.cursor; back > 0 {
+		fmt.Fprintf(c.out, "\x1b[%dD", back)
+	}
+}
+
+// ReadLine returns the next line that is typed, without the line break
+func (c *Console) ReadLine() (string, error) {
+	if !c.editing {
+		line, err := c.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	historyIndex := len(c.history)
+	var edited string // the line that was being typed before going through the history
+	for {
+		k, err := c.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		c.mu.Lock()
+		switch k.code {
+		case keyEnter:
+			line := string(c.line)
+			c.line, c.cursor = nil, 0
+			fmt.Fprint(c.out, "\r\x1b[K", Prompt, line, "\n")
+			if strings.TrimSpace(line) != "" && (len(c.history) == 0 || c.history[len(c.history)-1] != line) {
+				c.history = append(c.history, line)
+				if len(c.history) > maxHistory {
+					c.history = c.history[1:]
+				}
+			}
+			c.draw()
+			c.mu.Unlock()
+			return line, nil
+		case keyInterrupt:
+			c.line, c.cursor = nil, 0
+			c.mu.Unlock()
+			if c.OnInterrupt != nil {
+				c.OnInterrupt()
+			}
+			c.redraw()
+			continue
+		case keyEOF:
+			if len(c.line) == 0 {
+				c.mu.Unlock()
+				return "", io.EOF
+			}
+		case keyBackspace:
+			if c.cursor > 0 {
+				c.line = append(c.line[:c.cursor-1], c.line[c.cursor:]...)
+				c.cursor--
+			}
+		case keyDelete:
+			if c.cursor < len(c.line) {
+				c.line = append(c.line[:c.cursor], c.line[c.cursor+1:]...)
+			}
+		case keyLeft:
+			if c.cursor > 0 {
+				c.cursor--
+			}
+		case keyRight:
+			if c.cursor < len(c.line) {
+				c.cursor++
+			}
+		case keyHome:
+			c.cursor = 0
+		case keyEnd:
+			c.cursor = len(c.line)
+		case keyClear:
+			c.line, c.cursor = nil, 0
+		case keyUp, keyDown:
+			if historyIndex == len(c.history) {
+				edited = string(c.line)
+			}
+			if k.code == keyUp && historyIndex > 0 {
+				historyIndex--
+			} else if k.code == keyDown && historyIndex < len(c.history) {
+				historyIndex++
+			}
+			if historyIndex == len(c.history) {
+				c.line = []rune(edited)
+			} else {
+				c.line = []rune(c.history[historyIndex])
+			}
+			c.cursor = len(c.line)
+		case keyRune:
+			c.line = append(c.line[:c.cursor], append([]rune{k.r}, c.line[c.cursor:]...)...)
+			c.cursor++
+		}
+		fmt.Fprint(c.out, "\r\x1b[K")
+		c.draw()
+		c.mu.Unlock()
+	}
+}
+
+// Run executes every line that is typed with d, until the input ends
+func (c *Console) Run(d *command.Dispatcher) {
+	sender := consoleSender{c}
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				log.Errorf("failed to read from the console: %v", err)
+			}
+			return
+		}
+		line = strings.TrimPrefix(strings.TrimSpace(line), "/")
+		if line != "" {
+			_ = d.Execute(sender, line)
+		}
+	}
+}
+
+// consoleSender runs the commands of the console, which may run every command
+type consoleSender struct {
+	c *Console
+}
+
+func (s consoleSender) Name() string {
+	return Name
+}
+
+func (s consoleSender) SendMessage(msg chat.Component) {
+	fmt.Fprintln(s.c, msg.PlainText())
+}
+
+func (s consoleSender) HasPermission(node string) bool {
+	return true
+}
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyEnter
+	keyInterrupt
+	keyEOF
+	keyBackspace
+	keyDelete
+	keyLeft
+	keyRight
+	keyUp
+	keyDown
+	keyHome
+	keyEnd
+	keyClear
+	keyIgnored
+)
+
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// readKey reads the next key from a terminal in raw mode, which sends keys like arrows as escape sequences
+func (c *Console) readKey() (key, error) {
+	r, _, err := c.in.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	switch r {
+	case '\r', '\n':
+		return key{code: keyEnter}, nil
+	case 0x03: // Ctrl+C
+		return key{code: keyInterrupt}, nil
+	case 0x04: // Ctrl+D
+		return key{code: keyEOF}, nil
+	case 0x7f, 0x08:
+		return key{code: keyBackspace}, nil
+	case 0x01: // Ctrl+A
+		return key{code: keyHome}, nil
+	case 0x05: // Ctrl+E
+		return key{code: keyEnd}, nil
+	case 0x15: // Ctrl+U
+		return key{code: keyClear}, nil
+	case 0x1b:
+		return c.readEscape()
+	}
+	if !unicode.IsPrint(r) {
+		return key{code: keyIgnored}, nil
+	}
+	return key{code: keyRune, r: r}, nil
+}
+
+// readEscape reads the rest of an escape sequence like ESC [ A (up) or ESC [ 3 ~ (delete)
+func (c *Console) readEscape() (key, error) {
+	r, _, err := c.in.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	if r != '[' && r != 'O' {
+		return key{code: keyIgnored}, nil
+	}
+	var params []rune
+	for {
+		r, _, err = c.in.ReadRune()
+		if err != nil {
+			return key{}, err
+		}
+		if r >= 0x40 && r <= 0x7e { // final byte of the sequence
+			break
+		}
+		params = append(params, r)
+	}
+	switch {
+	case r == 'A':
+		return key{code: keyUp}, nil
+	case r == 'B':
+		return key{code: keyDown}, nil
+	case r == 'C':
+		return key{code: keyRight}, nil
+	case r == 'D':
+		return key{code: keyLeft}, nil
+	case r == 'H', r == '~' && (string(params) == "1" || string(params) == "7"):
+		return key{code: keyHome}, nil
+	case r == 'F', r == '~' && (string(params) == "4" || string(params) == "8"):
+		return key{code: keyEnd}, nil
+	case r == '~' && string(params) == "3":
+		return key{code: keyDelete}, nil
+	}
+	return key{code: keyIgnored}, nil
+}
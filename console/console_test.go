@@ -0,0 +1,48 @@
+package console
+
+import (
+	"bytes"
+	"github.com/masp/mcgo/command"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestConsole_ReadLine(t *testing.T) {
+	// Typing "helo", fixing it to "hello", then going back through the history and clearing a line
+	input := "helo\x1b[D\x1b[Dl\r" + // left twice and insert
+		"word\x7fld\r" + // backspace
+		"\x1b[A\x1b[A\r" + // up twice
+		"gone\x15\x1b[A\x1b[B\x1b[Bkept\r" + // Ctrl+U, then up and back down to what was typed
+		"\x04"
+	c := New(strings.NewReader(input), ioutil.Discard, true)
+	var lines []string
+	for {
+		line, err := c.ReadLine()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("ReadLine() error = %v", err)
+		}
+		lines = append(lines, line)
+	}
+	want := []string{"hello", "world", "hello", "kept"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("ReadLine() read %q, want %q", lines, want)
+	}
+}
+
+func TestConsole_Run(t *testing.T) {
+	var out bytes.Buffer
+	d := command.NewDispatcher()
+	_ = d.Register(&command.Command{Name: "op", Permission: "mc.command.op", Run: func(ctx *command.Context) error {
+		ctx.Reply("%s made %s an operator", ctx.Sender.Name(), ctx.Args[0])
+		return nil
+	}})
+
+	New(strings.NewReader("/op Alice\n\nop Bob"), &out, false).Run(d)
+	if want := "Server made Alice an operator\nServer made Bob an operator\n"; out.String() != want {
+		t.Errorf("Run() wrote %q, want %q", out.String(), want)
+	}
+}
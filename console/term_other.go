@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package console
+
+import (
+	"errors"
+)
+
+// makeRaw isn't supported on this platform, so the console reads whole lines like any other input
+func makeRaw(fd int) (func() error, error) {
+	return nil, errors.New("line editing isn't supported on this platform")
+}
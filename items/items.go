@@ -0,0 +1,66 @@
+package items
+
+//go:generate go run ../cmd/datagen -registries ../reports/registries.json -blocks ../reports/blocks.json -out registry_gen.go
+
+import (
+	"github.com/masp/mcgo/blocks"
+	"sort"
+)
+
+// ID is the protocol ID of an item, which is what's sent in slots
+type ID int32
+
+type Item struct {
+	Name string
+	ID   ID
+	// Block is the default state of the block the item places, if PlacesBlock is set
+	Block       blocks.State
+	PlacesBlock bool
+}
+
+var (
+	byName  = make(map[string]*Item, len(registry))
+	byBlock = make(map[string]*Item)
+)
+
+func init() {
+	for i := range registry {
+		it := &registry[i]
+		byName[it.Name] = it
+		if block, ok := blocks.BlockOf(it.Block); it.PlacesBlock && ok {
+			byBlock[block.Name] = it
+		}
+	}
+}
+
+// ByName returns the item with the namespaced name, e.g. minecraft:stone
+func ByName(name string) (*Item, bool) {
+	it, ok := byName[name]
+	return it, ok
+}
+
+func ByID(id ID) (*Item, bool) {
+	i := sort.Search(len(registry), func(i int) bool {
+		return registry[i].ID >= id
+	})
+	if i == len(registry) || registry[i].ID != id {
+		return nil, false
+	}
+	return &registry[i], true
+}
+
+// BlockState returns the state of the block that is placed when a player uses the item, or false if the item doesn't
+// place blocks.
+func (it *Item) BlockState() (blocks.State, bool) {
+	return it.Block, it.PlacesBlock
+}
+
+// ForBlock returns the item that places the block in state s (any state of the block), like for picking blocks
+func ForBlock(s blocks.State) (*Item, bool) {
+	block, ok := blocks.BlockOf(s)
+	if !ok {
+		return nil, false
+	}
+	it, ok := byBlock[block.Name]
+	return it, ok
+}
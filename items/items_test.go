@@ -0,0 +1,44 @@
+package items
+
+import (
+	"github.com/masp/mcgo/blocks"
+	"testing"
+)
+
+func TestItem_BlockState(t *testing.T) {
+	tests := []struct {
+		item        ID
+		want        blocks.State
+		placesBlock bool
+	}{
+		{Stone, blocks.Stone, true},
+		{GrassBlock, blocks.GrassBlock, true},
+		{OakLog, blocks.OakLog, true},
+		{WaterBucket, blocks.Water, true},
+		{Stick, 0, false},
+		{Air, 0, false},
+	}
+	for _, tt := range tests {
+		it, ok := ByID(tt.item)
+		if !ok {
+			t.Fatalf("ByID(%d) not found", tt.item)
+		}
+		if got, placesBlock := it.BlockState(); got != tt.want || placesBlock != tt.placesBlock {
+			t.Errorf("%s BlockState() = %d, %t, want %d, %t", it.Name, got, placesBlock, tt.want, tt.placesBlock)
+		}
+	}
+
+	if _, ok := ByID(5000); ok {
+		t.Errorf("ByID(5000) found, want not found")
+	}
+}
+
+func TestForBlock(t *testing.T) {
+	snowyGrass := blocks.MustGet("minecraft:grass_block", map[string]string{"snowy": "true"})
+	if it, ok := ForBlock(snowyGrass); !ok || it.ID != GrassBlock {
+		t.Errorf("ForBlock(snowy grass block) = %v, want minecraft:grass_block", it)
+	}
+	if it, ok := ByName("minecraft:sandstone"); !ok || it.ID != Sandstone {
+		t.Errorf("ByName(minecraft:sandstone) = %v, want ID %d", it, Sandstone)
+	}
+}
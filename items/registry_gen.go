@@ -0,0 +1,86 @@
+// Code generated by cmd/datagen from ../reports/registries.json; DO NOT EDIT.
+
+package items
+
+import "github.com/masp/mcgo/blocks"
+
+const (
+	Air              ID = 0
+	Stone            ID = 1
+	Granite          ID = 2
+	PolishedGranite  ID = 3
+	Diorite          ID = 4
+	PolishedDiorite  ID = 5
+	Andesite         ID = 6
+	PolishedAndesite ID = 7
+	GrassBlock       ID = 8
+	Dirt             ID = 9
+	CoarseDirt       ID = 10
+	Podzol           ID = 11
+	Cobblestone      ID = 14
+	OakPlanks        ID = 15
+	SprucePlanks     ID = 16
+	BirchPlanks      ID = 17
+	JunglePlanks     ID = 18
+	AcaciaPlanks     ID = 19
+	DarkOakPlanks    ID = 20
+	OakSapling       ID = 23
+	Bedrock          ID = 29
+	Sand             ID = 30
+	RedSand          ID = 31
+	Gravel           ID = 32
+	GoldOre          ID = 33
+	IronOre          ID = 34
+	CoalOre          ID = 35
+	OakLog           ID = 37
+	OakLeaves        ID = 69
+	Glass            ID = 77
+	Sandstone        ID = 81
+	Cobweb           ID = 87
+	ShortGrass       ID = 88
+	Stick            ID = 604
+	Bucket           ID = 648
+	WaterBucket      ID = 649
+	LavaBucket       ID = 650
+)
+
+// registry is sorted by item ID
+var registry = []Item{
+	{Name: "minecraft:air", ID: Air},
+	{Name: "minecraft:stone", ID: Stone, Block: blocks.Stone, PlacesBlock: true},
+	{Name: "minecraft:granite", ID: Granite, Block: blocks.Granite, PlacesBlock: true},
+	{Name: "minecraft:polished_granite", ID: PolishedGranite, Block: blocks.PolishedGranite, PlacesBlock: true},
+	{Name: "minecraft:diorite", ID: Diorite, Block: blocks.Diorite, PlacesBlock: true},
+	{Name: "minecraft:polished_diorite", ID: PolishedDiorite, Block: blocks.PolishedDiorite, PlacesBlock: true},
+	{Name: "minecraft:andesite", ID: Andesite, Block: blocks.Andesite, PlacesBlock: true},
+	{Name: "minecraft:polished_andesite", ID: PolishedAndesite, Block: blocks.PolishedAndesite, PlacesBlock: true},
+	{Name: "minecraft:grass_block", ID: GrassBlock, Block: blocks.GrassBlock, PlacesBlock: true},
+	{Name: "minecraft:dirt", ID: Dirt, Block: blocks.Dirt, PlacesBlock: true},
+	{Name: "minecraft:coarse_dirt", ID: CoarseDirt, Block: blocks.CoarseDirt, PlacesBlock: true},
+	{Name: "minecraft:podzol", ID: Podzol, Block: blocks.Podzol, PlacesBlock: true},
+	{Name: "minecraft:cobblestone", ID: Cobblestone, Block: blocks.Cobblestone, PlacesBlock: true},
+	{Name: "minecraft:oak_planks", ID: OakPlanks, Block: blocks.OakPlanks, PlacesBlock: true},
+	{Name: "minecraft:spruce_planks", ID: SprucePlanks, Block: blocks.SprucePlanks, PlacesBlock: true},
+	{Name: "minecraft:birch_planks", ID: BirchPlanks, Block: blocks.BirchPlanks, PlacesBlock: true},
+	{Name: "minecraft:jungle_planks", ID: JunglePlanks, Block: blocks.JunglePlanks, PlacesBlock: true},
+	{Name: "minecraft:acacia_planks", ID: AcaciaPlanks, Block: blocks.AcaciaPlanks, PlacesBlock: true},
+	{Name: "minecraft:dark_oak_planks", ID: DarkOakPlanks, Block: blocks.DarkOakPlanks, PlacesBlock: true},
+	{Name: "minecraft:oak_sapling", ID: OakSapling, Block: blocks.OakSapling, PlacesBlock: true},
+	{Name: "minecraft:bedrock", ID: Bedrock, Block: blocks.Bedrock, PlacesBlock: true},
+	{Name: "minecraft:sand", ID: Sand, Block: blocks.Sand, PlacesBlock: true},
+	{Name: "minecraft:red_sand", ID: RedSand, Block: blocks.RedSand, PlacesBlock: true},
+	{Name: "minecraft:gravel", ID: Gravel, Block: blocks.Gravel, PlacesBlock: true},
+	{Name: "minecraft:gold_ore", ID: GoldOre, Block: blocks.GoldOre, PlacesBlock: true},
+	{Name: "minecraft:iron_ore", ID: IronOre, Block: blocks.IronOre, PlacesBlock: true},
+	{Name: "minecraft:coal_ore", ID: CoalOre, Block: blocks.CoalOre, PlacesBlock: true},
+	{Name: "minecraft:oak_log", ID: OakLog, Block: blocks.OakLog, PlacesBlock: true},
+	{Name: "minecraft:oak_leaves", ID: OakLeaves, Block: blocks.OakLeaves, PlacesBlock: true},
+	{Name: "minecraft:glass", ID: Glass, Block: blocks.Glass, PlacesBlock: true},
+	{Name: "minecraft:sandstone", ID: Sandstone, Block: blocks.Sandstone, PlacesBlock: true},
+	{Name: "minecraft:cobweb", ID: Cobweb, Block: blocks.Cobweb, PlacesBlock: true},
+	{Name: "minecraft:grass", ID: ShortGrass, Block: blocks.ShortGrass, PlacesBlock: true},
+	{Name: "minecraft:stick", ID: Stick},
+	{Name: "minecraft:bucket", ID: Bucket},
+	{Name: "minecraft:water_bucket", ID: WaterBucket, Block: blocks.Water, PlacesBlock: true},
+	{Name: "minecraft:lava_bucket", ID: LavaBucket, Block: blocks.Lava, PlacesBlock: true},
+}
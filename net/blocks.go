@@ -0,0 +1,128 @@
+package net
+
+import (
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/items"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+)
+
+// Permissions that are needed to change blocks
+const (
+	BreakBlockPermission = "mc.block.break"
+	PlaceBlockPermission = "mc.block.place"
+)
+
+// Statuses of Player Digging
+const (
+	startedDigging   = 0
+	cancelledDigging = 1
+	finishedDigging  = 2
+)
+
+// maxReach is how far away players can change blocks, a bit more than the client allows to account for lag
+const maxReach = 8
+
+// acknowledgeDigging (Acknowledge Player Digging) tells the client if breaking the block worked, and the block that is
+// there now so it can undo what it predicted
+type acknowledgeDigging struct {
+	Pos        pstn.Block
+	State      chunks.BlockState
+	Status     int32
+	Successful bool
+}
+
+func (a acknowledgeDigging) EncodeTo(e *proto.PacketEncoder) {
+	e.WritePosition(a.Pos)
+	e.WriteVar32(int32(a.State))
+	e.WriteVar32(a.Status)
+	e.WriteBool(a.Successful)
+}
+
+func (p *Player) canReach(pos pstn.Block) bool {
+	dx := float64(pos.X) + 0.5 - p.FeetPos.X
+	dy := float64(pos.Y) + 0.5 - p.FeetPos.Y
+	dz := float64(pos.Z) + 0.5 - p.FeetPos.Z
+	return dx*dx+dy*dy+dz*dz <= maxReach*maxReach
+}
+
+// handleDigging breaks the block once the player is done digging, which is right away in creative. Blocks that
+// players may not break stay, and the client is told to put them back. The block is changed on the tick goroutine.
+func (p *Player) handleDigging(status int32, pos pstn.Block) {
+	if status != startedDigging && status != cancelledDigging && status != finishedDigging {
+		return // dropping items, eating and swapping hands aren't about blocks
+	}
+	var breaks bool
+	switch p.Gamemode() {
+	case Creative:
+		breaks = status == startedDigging
+	case Survival:
+		breaks = status == finishedDigging
+	}
+
+	world := p.World()
+	ok := breaks && p.canReach(pos) && p.HasPermission(BreakBlockPermission)
+	world.Submit(func() {
+		if ok {
+			world.SetBlockAt(pos, blocks.Air)
+		}
+		p.SendPacket(acknowledgeDiggingID, acknowledgeDigging{
+			Pos:        pos,
+			State:      world.BlockAt(pos),
+			Status:     status,
+			Successful: ok || !breaks,
+		})
+	})
+}
+
+// faceOffsets are the blocks next to a block by the face of Player Block Placement: bottom, top, north, south, west
+// and east
+var faceOffsets = [6]pstn.Block{{Y: -1}, {Y: 1}, {Z: -1}, {Z: 1}, {X: -1}, {X: 1}}
+
+// replaceable returns true if a placed block can take the place of the block in state
+func replaceable(state chunks.BlockState) bool {
+	block, ok := blocks.BlockOf(state)
+	if !ok {
+		return false
+	}
+	switch block.Name {
+	case "minecraft:air", "minecraft:cave_air", "minecraft:void_air", "minecraft:water", "minecraft:lava":
+		return true
+	}
+	return false
+}
+
+// handlePlacement places the block of the held item against the face of the block that was clicked. If the player
+// may not place it there, the client is sent the block that is there so it removes the one it predicted. The block is
+// changed on the tick goroutine.
+func (p *Player) handlePlacement(offhand bool, against pstn.Block, face int32) {
+	if face < 0 || int(face) >= len(faceOffsets) {
+		return
+	}
+	offset := faceOffsets[face]
+	pos := pstn.Block{X: against.X + offset.X, Y: against.Y + offset.Y, Z: against.Z + offset.Z}
+
+	held := p.ItemInHand(offhand)
+	if held.Empty() || p.Gamemode() == Adventure || p.Gamemode() == Spectator {
+		return
+	}
+	item, ok := items.ByID(held.Item)
+	if !ok {
+		return
+	}
+	state, places := item.BlockState()
+	if !places {
+		return
+	}
+
+	world := p.World()
+	allowed := pos.Y >= 0 && pos.Y < chunks.Height && p.canReach(pos) && p.HasPermission(PlaceBlockPermission)
+	world.Submit(func() {
+		if allowed && replaceable(world.BlockAt(pos)) {
+			world.SetBlockAt(pos, state)
+			return
+		}
+		p.SendPacket(blockChangeID, chunks.BlockChangePacket{Pos: pos, State: world.BlockAt(pos)})
+	})
+}
@@ -0,0 +1,200 @@
+package net
+
+import (
+	"bytes"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/items"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	"github.com/masp/mcgo/worlds"
+	uuid "github.com/satori/go.uuid"
+	"testing"
+)
+
+type testPermissions map[string]bool
+
+func (t testPermissions) HasPermission(id uuid.UUID, node string) bool {
+	return t[node]
+}
+
+// newTestPlayer returns a player standing at the origin of a ticking world with the chunk around it loaded. Packets
+// sent to the player are kept in its queue, see sentPackets.
+func newTestPlayer(t *testing.T, gamemode Gamemode, perms testPermissions) *Player {
+	world := worlds.New(worlds.Config{Workers: 1})
+	world.Chunk(pstn.Chunk{})
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		world.Run(stop)
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		<-stopped
+		world.Close()
+	})
+
+	server := NewServer(worlds.NewManager(""))
+	server.Permissions = perms
+	return &Player{
+		server:        server,
+		world:         world,
+		gamemode:      gamemode,
+		FeetPos:       pstn.Entity{X: 0.5, Y: 10, Z: 0.5},
+		packetsToSend: make(chan []byte, defaultSendPacketsBuffered),
+	}
+}
+
+// waitForTick waits until everything that was submitted to the world ran
+func waitForTick(world *worlds.Dimension) {
+	done := make(chan struct{})
+	world.Submit(func() { close(done) })
+	<-done
+}
+
+// sentPackets takes the packets that were queued for the player
+func sentPackets(p *Player) []*proto.PacketDecoder {
+	var sent []*proto.PacketDecoder
+	for {
+		select {
+		case data := <-p.packetsToSend:
+			sent = append(sent, proto.NewPacketDecoder(bytes.NewReader(data)))
+		default:
+			return sent
+		}
+	}
+}
+
+// readAcknowledgement reads the packet as Acknowledge Player Digging and returns if it was successful
+func readAcknowledgement(t *testing.T, d *proto.PacketDecoder) bool {
+	t.Helper()
+	if id := d.ReadVar32(); id != acknowledgeDiggingID {
+		t.Fatalf("sent packet 0x%02x, want Acknowledge Player Digging", id)
+	}
+	d.ReadPosition()
+	d.ReadVar32()
+	d.ReadVar32()
+	return d.ReadBool()
+}
+
+func TestPlayer_handleDigging(t *testing.T) {
+	allowed := testPermissions{BreakBlockPermission: true}
+	near := pstn.Block{X: 1, Y: 8, Z: 1}
+	tests := []struct {
+		name     string
+		gamemode Gamemode
+		perms    testPermissions
+		pos      pstn.Block
+		statuses []int32
+		breaks   bool
+	}{
+		{"creative breaks right away", Creative, allowed, near, []int32{startedDigging}, true},
+		{"survival breaks when done", Survival, allowed, near, []int32{startedDigging, finishedDigging}, true},
+		{"survival doesn't break when cancelled", Survival, allowed, near, []int32{startedDigging, cancelledDigging}, false},
+		{"adventure can't break", Adventure, allowed, near, []int32{startedDigging, finishedDigging}, false},
+		{"out of reach", Creative, allowed, pstn.Block{X: 12, Y: 8, Z: 1}, []int32{startedDigging}, false},
+		{"without permission", Creative, testPermissions{}, near, []int32{startedDigging}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlayer(t, tt.gamemode, tt.perms)
+			world := p.World()
+			world.SetBlockAt(tt.pos, blocks.Stone)
+
+			for _, status := range tt.statuses {
+				p.handleDigging(status, tt.pos)
+			}
+			waitForTick(world)
+			if broken := world.BlockAt(tt.pos) == blocks.Air; broken != tt.breaks {
+				t.Errorf("block broken = %v, want %v", broken, tt.breaks)
+			}
+			sent := sentPackets(p)
+			if len(sent) != len(tt.statuses) {
+				t.Fatalf("sent %d packets, want an acknowledgement for each status", len(sent))
+			}
+			last := readAcknowledgement(t, sent[len(sent)-1])
+			if tt.breaks && !last {
+				t.Errorf("breaking the block wasn't acknowledged")
+			}
+		})
+	}
+
+	t.Run("denied break is undone", func(t *testing.T) {
+		p := newTestPlayer(t, Creative, testPermissions{})
+		p.handleDigging(startedDigging, near)
+		waitForTick(p.World())
+		if ok := readAcknowledgement(t, sentPackets(p)[0]); ok {
+			t.Errorf("denied break was acknowledged as successful")
+		}
+	})
+}
+
+func TestPlayer_handlePlacement(t *testing.T) {
+	allowed := testPermissions{PlaceBlockPermission: true}
+	against := pstn.Block{X: 1, Y: 7, Z: 1}
+	above := pstn.Block{X: 1, Y: 8, Z: 1}
+	tests := []struct {
+		name     string
+		gamemode Gamemode
+		perms    testPermissions
+		against  pstn.Block
+		places   bool
+		undone   bool // the client is sent the block that is there instead
+	}{
+		{"creative", Creative, allowed, against, true, false},
+		{"survival", Survival, allowed, against, true, false},
+		{"adventure", Adventure, allowed, against, false, false},
+		{"out of reach", Creative, allowed, pstn.Block{X: 1, Y: 7, Z: 12}, false, true},
+		{"without permission", Creative, testPermissions{}, against, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlayer(t, tt.gamemode, tt.perms)
+			p.inventory.slots[HotbarStart] = Slot{Item: items.Stone, Count: 1}
+			world := p.World()
+			target := pstn.Block{X: tt.against.X, Y: tt.against.Y + 1, Z: tt.against.Z}
+			world.SetBlockAt(tt.against, blocks.Dirt)
+			world.SetBlockAt(target, blocks.Air)
+
+			p.handlePlacement(false, tt.against, 1) // top face
+			waitForTick(world)
+			if placed := world.BlockAt(target) == blocks.Stone; placed != tt.places {
+				t.Errorf("block placed = %v, want %v", placed, tt.places)
+			}
+			sent := sentPackets(p)
+			if undone := len(sent) == 1 && sent[0].ReadVar32() == blockChangeID; undone != tt.undone {
+				t.Errorf("placement undone = %v, want %v", undone, tt.undone)
+			}
+		})
+	}
+
+	t.Run("occupied", func(t *testing.T) {
+		p := newTestPlayer(t, Creative, allowed)
+		p.inventory.slots[HotbarStart] = Slot{Item: items.Stone, Count: 1}
+		p.World().SetBlockAt(above, blocks.OakPlanks)
+		p.handlePlacement(false, against, 1)
+		waitForTick(p.World())
+		if got := p.World().BlockAt(above); got != blocks.OakPlanks {
+			t.Errorf("placing into a block replaced it with %d", got)
+		}
+	})
+}
+
+func TestPlayer_setCreativeSlot(t *testing.T) {
+	stone := Slot{Item: items.Stone, Count: 64}
+	p := newTestPlayer(t, Survival, nil)
+	p.setCreativeSlot(HotbarStart, stone)
+	if !p.ItemInHand(false).Empty() {
+		t.Errorf("survival player took items from the creative inventory")
+	}
+
+	p = newTestPlayer(t, Creative, nil)
+	p.setCreativeSlot(HotbarStart+2, stone)
+	p.selectSlot(2)
+	if got := p.ItemInHand(false); got.Item != stone.Item || got.Count != stone.Count {
+		t.Errorf("ItemInHand() = %+v, want %+v", got, stone)
+	}
+	p.selectSlot(HotbarSize)
+	if p.HeldSlot() != 2 {
+		t.Errorf("selected a slot outside of the hotbar")
+	}
+}
@@ -0,0 +1,96 @@
+package net
+
+import (
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/proto"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+const (
+	chatMessageServerboundID = 0x03
+	chatMessageClientboundID = 0x0E
+	playDisconnectID         = 0x19
+)
+
+// Positions of chat messages on the client
+const (
+	chatPosition   = 0 // a message of a player
+	systemPosition = 1 // feedback of commands and other messages from the server
+)
+
+// maxChatLength is the longest message the client sends, longer ones are from modified clients
+const maxChatLength = 256
+
+// chatMessage (Chat Message) shows a message on the client
+type chatMessage struct {
+	Message  chat.Component
+	Position uint8
+	Sender   uuid.UUID // the player that sent it, used by clients to hide messages of blocked players
+}
+
+func (c chatMessage) EncodeTo(e *proto.PacketEncoder) {
+	e.WriteString(c.Message.String())
+	e.WriteU8(c.Position)
+	e.WriteUUID(c.Sender)
+}
+
+// playDisconnect (Disconnect) tells the client why it's disconnected once it's playing
+type playDisconnect struct {
+	Reason chat.Component
+}
+
+func (d playDisconnect) EncodeTo(e *proto.PacketEncoder) {
+	e.WriteString(d.Reason.String())
+}
+
+// Name returns the username of the player
+func (p *Player) Name() string {
+	return p.Username
+}
+
+// SendMessage shows a message from the server in the chat of the player
+func (p *Player) SendMessage(msg chat.Component) {
+	p.SendPacket(chatMessageClientboundID, chatMessage{Message: msg, Position: systemPosition})
+}
+
+// Broadcast shows a message from the server to every player that is online
+func (s *Server) Broadcast(msg chat.Component) {
+	log.Info(msg.PlainText())
+	for _, p := range s.Players() {
+		p.SendMessage(msg)
+	}
+}
+
+// handleChat either runs a command, if the message starts with a slash, or sends the message to every player
+func (p *Player) handleChat(msg string) {
+	msg = strings.TrimSpace(msg)
+	if msg == "" || len(msg) > maxChatLength {
+		return
+	}
+	if strings.HasPrefix(msg, "/") {
+		if p.server.OnCommand == nil {
+			p.SendMessage(chat.Colored("Unknown command", "red"))
+			return
+		}
+		p.server.OnCommand(p, msg[1:])
+		return
+	}
+
+	line := chat.Text("<" + p.Username + "> " + msg)
+	log.Info(line.PlainText())
+	for _, other := range p.server.Players() {
+		other.SendPacket(chatMessageClientboundID, chatMessage{Message: line, Position: chatPosition, Sender: p.UUID})
+	}
+}
+
+// Kick disconnects the player with a reason, once the packets that were already queued are sent
+func (p *Player) Kick(reason chat.Component) {
+	log.Infof("Kicked %s: %s", p.Username, reason.PlainText())
+	p.queuePacket(playDisconnectID, playDisconnect{reason})
+	select {
+	case p.packetsToSend <- nil: // tells handleSendingPackets to close the connection
+	case <-p.stopped:
+	}
+}
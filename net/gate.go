@@ -0,0 +1,144 @@
+package net
+
+import (
+	"crypto/md5"
+	"github.com/masp/mcgo/chat"
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"time"
+)
+
+// LoginRequest is a player trying to join the server
+type LoginRequest struct {
+	Username string
+	UUID     uuid.UUID
+	Addr     net.Addr
+}
+
+// IP returns the address the player connects from, or nil if it isn't an IP connection
+func (r LoginRequest) IP() net.IP {
+	return addrIP(r.Addr)
+}
+
+// IP returns the address the player is connected from, or nil if it isn't an IP connection
+func (p *Player) IP() net.IP {
+	return addrIP(p.Conn.RemoteAddr())
+}
+
+func addrIP(addr net.Addr) net.IP {
+	if addr, ok := addr.(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
+// Ban is why and until when a player or an address is banned
+type Ban struct {
+	Reason  string
+	Expires time.Time // zero if the ban doesn't expire
+}
+
+// BanList knows who is banned. Expired bans shouldn't be returned.
+type BanList interface {
+	PlayerBan(id uuid.UUID) (Ban, bool)
+	IPBan(ip net.IP) (Ban, bool)
+}
+
+// Whitelist knows who may join while the whitelist is enabled
+type Whitelist interface {
+	Whitelisted(id uuid.UUID) bool
+}
+
+// OpList knows who the operators are. Like in vanilla, operators may join while the whitelist doesn't list them, and
+// those that bypass the player limit even when the server is full.
+type OpList interface {
+	Level(id uuid.UUID) int // 0 if the player isn't an operator
+	BypassesPlayerLimit(id uuid.UUID) bool
+}
+
+// PreLoginHook decides if a player may join after the server let them in. It returns nil to let them join, or the
+// reason they are disconnected with.
+type PreLoginHook func(req LoginRequest) *chat.Component
+
+// Messages players are disconnected with when they can't join, the same as vanilla
+const (
+	ServerFullMessage     = "The server is full!"
+	NotWhitelistedMessage = "You are not white-listed on this server!"
+	ShutdownMessage       = "Server closed"
+	banDateFormat         = "2006-01-02 15:04:05 -0700"
+)
+
+// PlayerBannedMessage is what banned players are disconnected with
+func PlayerBannedMessage(ban Ban) chat.Component {
+	return bannedMessage("You are banned from this server.", ban)
+}
+
+// IPBannedMessage is what players from banned addresses are disconnected with
+func IPBannedMessage(ban Ban) chat.Component {
+	return bannedMessage("Your IP address is banned from this server.", ban)
+}
+
+func bannedMessage(prefix string, ban Ban) chat.Component {
+	text := prefix + "\nReason: " + ban.Reason
+	if !ban.Expires.IsZero() {
+		text += "\nYour ban will be removed on " + ban.Expires.Format(banDateFormat)
+	}
+	return chat.Text(text)
+}
+
+// checkLogin returns why a player can't join, or nil if they can. Like vanilla, bans are checked before the
+// whitelist, which operators don't need to be on. Whether the server is full is checked when the player is added, see
+// join.
+func (s *Server) checkLogin(req LoginRequest) *chat.Component {
+	if s.Bans != nil {
+		if ban, ok := s.Bans.PlayerBan(req.UUID); ok {
+			msg := PlayerBannedMessage(ban)
+			return &msg
+		}
+		if ip := req.IP(); ip != nil {
+			if ban, ok := s.Bans.IPBan(ip); ok {
+				msg := IPBannedMessage(ban)
+				return &msg
+			}
+		}
+	}
+	if s.Whitelist != nil && !s.Whitelist.Whitelisted(req.UUID) && !s.isOp(req.UUID) {
+		msg := chat.Text(NotWhitelistedMessage)
+		return &msg
+	}
+	if s.PreLogin != nil {
+		return s.PreLogin(req)
+	}
+	return nil
+}
+
+// join adds the player to the online players. It returns why they can't join if the server is full or shutting
+// down, or nil if they joined. Operators that bypass the player limit can join a full server.
+func (s *Server) join(p *Player) *chat.Component {
+	s.playersMu.Lock()
+	defer s.playersMu.Unlock()
+	if s.closed {
+		msg := chat.Text(ShutdownMessage)
+		return &msg
+	}
+	if len(s.players) >= s.MaxPlayers && (s.Ops == nil || !s.Ops.BypassesPlayerLimit(p.UUID)) {
+		msg := chat.Text(ServerFullMessage)
+		return &msg
+	}
+	s.players[p] = struct{}{}
+	s.sessions.Add(1)
+	return nil
+}
+
+func (s *Server) isOp(id uuid.UUID) bool {
+	return s.Ops != nil && s.Ops.Level(id) > 0
+}
+
+// OfflineUUID returns the UUID a player gets without authentication, which is the same one vanilla servers in offline
+// mode give them
+func OfflineUUID(username string) uuid.UUID {
+	id := uuid.UUID(md5.Sum([]byte("OfflinePlayer:" + username)))
+	id.SetVersion(uuid.V3)
+	id.SetVariant(uuid.VariantRFC4122)
+	return id
+}
@@ -0,0 +1,118 @@
+package net
+
+import (
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/worlds"
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"testing"
+	"time"
+)
+
+type testBans struct {
+	players map[uuid.UUID]Ban
+	ips     map[string]Ban
+}
+
+func (b testBans) PlayerBan(id uuid.UUID) (Ban, bool) {
+	ban, ok := b.players[id]
+	return ban, ok
+}
+
+func (b testBans) IPBan(ip net.IP) (Ban, bool) {
+	ban, ok := b.ips[ip.String()]
+	return ban, ok
+}
+
+type testWhitelist map[uuid.UUID]bool
+
+func (w testWhitelist) Whitelisted(id uuid.UUID) bool {
+	return w[id]
+}
+
+// testOps has the level of every operator, and bypasses the player limit for those above level 3
+type testOps map[uuid.UUID]int
+
+func (o testOps) Level(id uuid.UUID) int {
+	return o[id]
+}
+
+func (o testOps) BypassesPlayerLimit(id uuid.UUID) bool {
+	return o[id] > 3
+}
+
+func TestServer_checkLogin(t *testing.T) {
+	notch, jeb := OfflineUUID("Notch"), OfflineUUID("jeb_")
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	bans := testBans{
+		players: map[uuid.UUID]Ban{
+			notch: {Reason: "Griefing"},
+			jeb:   {Reason: "Spam", Expires: expires},
+		},
+		ips: map[string]Ban{"10.0.0.66": {Reason: "Bots"}},
+	}
+	denied := chat.Text("not today")
+	tests := []struct {
+		name      string
+		req       LoginRequest
+		bans      BanList
+		whitelist Whitelist
+		ops       OpList
+		preLogin  PreLoginHook
+		want      string // empty if the player may join
+	}{
+		{"nothing set", LoginRequest{UUID: notch}, nil, nil, nil, nil, ""},
+		{"banned", LoginRequest{UUID: notch}, bans, nil, nil, nil,
+			"You are banned from this server.\nReason: Griefing"},
+		{"banned until", LoginRequest{UUID: jeb}, bans, nil, nil, nil,
+			"You are banned from this server.\nReason: Spam\nYour ban will be removed on 2030-01-02 03:04:05 +0000"},
+		{"ip banned", LoginRequest{UUID: OfflineUUID("Dinnerbone"), Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 66)}},
+			bans, nil, nil, nil, "Your IP address is banned from this server.\nReason: Bots"},
+		{"not banned", LoginRequest{UUID: OfflineUUID("Dinnerbone"), Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)}},
+			bans, nil, nil, nil, ""},
+		{"bans before whitelist", LoginRequest{UUID: notch}, bans, testWhitelist{}, nil, nil,
+			"You are banned from this server.\nReason: Griefing"},
+		{"not whitelisted", LoginRequest{UUID: notch}, nil, testWhitelist{jeb: true}, nil, nil, NotWhitelistedMessage},
+		{"whitelisted", LoginRequest{UUID: jeb}, nil, testWhitelist{jeb: true}, nil, nil, ""},
+		{"op isn't whitelisted", LoginRequest{UUID: notch}, nil, testWhitelist{}, testOps{notch: 1}, nil, ""},
+		{"other op", LoginRequest{UUID: notch}, nil, testWhitelist{}, testOps{jeb: 4}, nil, NotWhitelistedMessage},
+		{"banned op", LoginRequest{UUID: notch}, bans, testWhitelist{}, testOps{notch: 4}, nil,
+			"You are banned from this server.\nReason: Griefing"},
+		{"pre login denies", LoginRequest{UUID: jeb}, nil, nil, nil,
+			func(LoginRequest) *chat.Component { return &denied }, "not today"},
+		{"pre login after whitelist", LoginRequest{UUID: notch}, nil, testWhitelist{}, nil,
+			func(LoginRequest) *chat.Component { return nil }, NotWhitelistedMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(worlds.NewManager(""))
+			server.Bans, server.Whitelist, server.Ops, server.PreLogin = tt.bans, tt.whitelist, tt.ops, tt.preLogin
+			got := server.checkLogin(tt.req)
+			if tt.want == "" && got != nil {
+				t.Errorf("checkLogin() = %q, want nil", got.PlainText())
+			} else if tt.want != "" && (got == nil || got.PlainText() != tt.want) {
+				t.Errorf("checkLogin() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_join(t *testing.T) {
+	server := NewServer(worlds.NewManager(""))
+	server.MaxPlayers = 1
+	if msg := server.join(&Player{}); msg != nil {
+		t.Fatalf("join() = %q, want nil", msg.PlainText())
+	}
+	if msg := server.join(&Player{}); msg == nil || msg.PlainText() != ServerFullMessage {
+		t.Errorf("join() of a full server = %v, want %q", msg, ServerFullMessage)
+	}
+
+	notch, jeb := OfflineUUID("Notch"), OfflineUUID("jeb_")
+	server.Ops = testOps{notch: 4, jeb: 2}
+	if msg := server.join(&Player{UUID: jeb}); msg == nil || msg.PlainText() != ServerFullMessage {
+		t.Errorf("join() of an op without bypassesPlayerLimit = %v, want %q", msg, ServerFullMessage)
+	}
+	if msg := server.join(&Player{UUID: notch}); msg != nil {
+		t.Errorf("join() of an op that bypasses the player limit = %q, want nil", msg.PlainText())
+	}
+}
@@ -0,0 +1,129 @@
+package net
+
+import (
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/items"
+	"github.com/masp/mcgo/proto"
+	"sync"
+)
+
+// Slots of the player inventory, numbered like its window: crafting, armor, the main inventory, the hotbar and the
+// offhand
+const (
+	InventorySize = 46
+	HotbarStart   = 36
+	HotbarSize    = 9
+	OffhandSlot   = 45
+)
+
+// Slot is a stack of items, it's empty if Count is 0
+type Slot struct {
+	Item  items.ID
+	Count int8
+	NBT   map[string]interface{} // extra data like enchantments, nil if there is none
+}
+
+func (s Slot) Empty() bool {
+	return s.Count <= 0
+}
+
+func readSlot(p *proto.RecvPacket) Slot {
+	if !p.ReadBool() {
+		return Slot{}
+	}
+	s := Slot{Item: items.ID(p.ReadVar32()), Count: p.ReadI8()}
+	var data map[string]interface{}
+	if p.ReadNBT(&data) {
+		s.NBT = data
+	}
+	return s
+}
+
+func writeSlot(e *proto.PacketEncoder, s Slot) {
+	if s.Empty() {
+		e.WriteBool(false)
+		return
+	}
+	e.WriteBool(true)
+	e.WriteVar32(int32(s.Item))
+	e.WriteI8(s.Count)
+	if s.NBT == nil {
+		e.WriteU8(0) // no NBT
+	} else {
+		e.WriteCompound(s.NBT)
+	}
+}
+
+// windowItems (Window Items) sets every slot of a window, window 0 is the inventory of the player
+type windowItems struct {
+	Window uint8
+	Slots  []Slot
+}
+
+func (w windowItems) EncodeTo(e *proto.PacketEncoder) {
+	e.WriteU8(w.Window)
+	e.WriteI16(int16(len(w.Slots)))
+	for _, s := range w.Slots {
+		writeSlot(e, s)
+	}
+}
+
+// inventory are the items of a player. Only creative players change it, by taking items out of the creative menu.
+type inventory struct {
+	mu    sync.Mutex
+	slots [InventorySize]Slot
+	held  int // selected hotbar slot, 0 to 8
+	// kept are saved items that can't be put in the inventory, because the item isn't in the registry or the slot
+	// isn't in the window. They are saved as they were until their slot is used.
+	kept []anvil.PlayerItem
+}
+
+// Inventory returns the items in the inventory of the player
+func (p *Player) Inventory() [InventorySize]Slot {
+	p.inventory.mu.Lock()
+	defer p.inventory.mu.Unlock()
+	return p.inventory.slots
+}
+
+// HeldSlot returns the selected hotbar slot, 0 to 8
+func (p *Player) HeldSlot() int {
+	p.inventory.mu.Lock()
+	defer p.inventory.mu.Unlock()
+	return p.inventory.held
+}
+
+// ItemInHand returns the items the player holds in their main hand, or their offhand
+func (p *Player) ItemInHand(offhand bool) Slot {
+	p.inventory.mu.Lock()
+	defer p.inventory.mu.Unlock()
+	if offhand {
+		return p.inventory.slots[OffhandSlot]
+	}
+	return p.inventory.slots[HotbarStart+p.inventory.held]
+}
+
+// sendInventory sends the player every item in their inventory
+func (p *Player) sendInventory() {
+	slots := p.Inventory()
+	p.sendPacketImmediately(windowItemsID, windowItems{Window: 0, Slots: slots[:]})
+}
+
+func (p *Player) selectSlot(slot int16) {
+	if slot < 0 || slot >= HotbarSize {
+		return
+	}
+	p.inventory.mu.Lock()
+	defer p.inventory.mu.Unlock()
+	p.inventory.held = int(slot)
+}
+
+// setCreativeSlot handles Creative Inventory Action, which creative players send when they put items in their
+// inventory. Negative slots are items dropped out of the inventory.
+func (p *Player) setCreativeSlot(slot int16, s Slot) {
+	if p.Gamemode() != Creative || slot < 0 || slot >= InventorySize {
+		return
+	}
+	p.inventory.mu.Lock()
+	defer p.inventory.mu.Unlock()
+	p.inventory.slots[slot] = s
+}
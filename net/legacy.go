@@ -0,0 +1,64 @@
+package net
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	legacyPingID     = 0xFE
+	legacyKickID     = 0xFF
+	legacyPingMarker = 0x01 // sent after the ping ID since 1.4
+	// legacyProtocolVersion makes old clients show the server as incompatible, like vanilla does
+	legacyProtocolVersion = 127
+)
+
+// isLegacyPing returns true if the client is pinging the server like clients before 1.7 did. Those start with a byte
+// that is never the start of a handshake, since no handshake is that long.
+func isLegacyPing(player *Player) bool {
+	first, err := player.socketDecoder.Reader.(*bufio.Reader).Peek(1)
+	if err != nil {
+		panic(err)
+	}
+	return first[0] == legacyPingID
+}
+
+// handleLegacyPing answers a ping of a client before 1.7 with the status of the server in a kick packet. There are
+// three versions of the ping:
+//
+//	FE        beta 1.8 to 1.3, answered with the MOTD and player counts separated by §
+//	FE 01     1.4 and 1.5, answered with §1 and the fields separated by null characters
+//	FE 01 FA  1.6, which sends the address it's connecting to after it and gets the same answer as 1.4
+//
+// Only the data that was received along with the first byte is looked at, like vanilla does.
+func handleLegacyPing(server *Server, player *Player) {
+	r := player.socketDecoder.Reader.(*bufio.Reader)
+	_, _ = r.ReadByte()
+	modern := false
+	if r.Buffered() > 0 {
+		marker, _ := r.ReadByte()
+		modern = marker == legacyPingMarker
+	}
+
+	status := server.Status(player.Conn.RemoteAddr())
+	motd := status.Description.PlainText()
+	var resp string
+	if modern {
+		resp = fmt.Sprintf("§1\x00%d\x00%s\x00%s\x00%d\x00%d",
+			legacyProtocolVersion, status.VersionName, motd, status.OnlinePlayers, status.MaxPlayers)
+	} else {
+		// § separates the fields, so it can't be in the MOTD
+		resp = fmt.Sprintf("%s§%d§%d", strings.ReplaceAll(motd, "§", ""), status.OnlinePlayers, status.MaxPlayers)
+	}
+
+	chars := utf16.Encode([]rune(resp))
+	e := player.socketEncoder
+	e.WriteU8(legacyKickID)
+	e.WriteU16(uint16(len(chars)))
+	for _, c := range chars {
+		e.WriteU16(c)
+	}
+	e.Flush()
+}
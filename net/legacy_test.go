@@ -0,0 +1,67 @@
+package net
+
+import (
+	"encoding/binary"
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/worlds"
+	"io/ioutil"
+	"net"
+	"testing"
+	"unicode/utf16"
+)
+
+// legacyPing sends ping to the server like an old client and returns the text of the kick packet it answers with
+func legacyPing(t *testing.T, server *Server, ping []byte) string {
+	t.Helper()
+	client, conn := net.Pipe()
+	defer client.Close()
+	go HandlePlayer(server, conn)
+
+	if _, err := client.Write(ping); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ioutil.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) < 3 || resp[0] != legacyKickID {
+		t.Fatalf("answered with % x, want a kick packet", resp)
+	}
+	n := int(binary.BigEndian.Uint16(resp[1:]))
+	if len(resp) != 3+2*n {
+		t.Fatalf("kick packet has %d bytes for %d characters", len(resp), n)
+	}
+	chars := make([]uint16, n)
+	for i := range chars {
+		chars[i] = binary.BigEndian.Uint16(resp[3+2*i:])
+	}
+	return string(utf16.Decode(chars))
+}
+
+func TestHandleLegacyPing(t *testing.T) {
+	server := NewServer(worlds.NewManager(""))
+	server.MOTD = chat.Text("A §cserver")
+
+	// 1.6 sends the plugin message MC|PingHost with the address it connects to
+	host := []byte{0xfa, 0x00, 0x0b}
+	for _, c := range utf16.Encode([]rune("MC|PingHost")) {
+		host = append(host, byte(c>>8), byte(c))
+	}
+	modern := "§1\x00127\x00" + VersionName + "\x00A §cserver\x000\x0020"
+	tests := []struct {
+		name string
+		ping []byte
+		want string
+	}{
+		{"beta 1.8 to 1.3", []byte{0xfe}, "A cserver§0§20"},
+		{"1.4 and 1.5", []byte{0xfe, 0x01}, modern},
+		{"1.6", append([]byte{0xfe, 0x01}, host...), modern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := legacyPing(t, server, tt.ping); got != tt.want {
+				t.Errorf("answered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,22 @@
 package net
 
 import (
+	"github.com/masp/mcgo/chat"
 	"github.com/masp/mcgo/proto"
-	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
 	loginStartID = 0x00
 )
 
+// Clientbound
+const (
+	loginDisconnectID = 0x00
+	loginSuccessID    = 0x02
+	setCompressionID  = 0x03
+)
+
 type LoginSuccess struct {
 	player *Player
 }
@@ -18,13 +26,45 @@ func (l LoginSuccess) EncodeTo(e *proto.PacketEncoder) {
 	e.WriteString(l.player.Username)
 }
 
-func handleLogin(player *Player) {
+type loginDisconnect struct {
+	Reason chat.Component
+}
+
+func (l loginDisconnect) EncodeTo(e *proto.PacketEncoder) {
+	e.WriteString(l.Reason.String())
+}
+
+// handleLogin logs the player in and adds them to the online players. If they aren't allowed to join, they are
+// disconnected and false is returned.
+func handleLogin(server *Server, player *Player) bool {
 	start := player.readPacket()
 	if start.ID != loginStartID {
 		panic("expected login start packet ID")
 	}
 
 	player.Username = start.ReadString()
-	player.UUID = uuid.NewV4()
-	player.sendPacketImmediately(0x02, LoginSuccess{player})
+	player.UUID = OfflineUUID(player.Username)
+	req := LoginRequest{Username: player.Username, UUID: player.UUID, Addr: player.Conn.RemoteAddr()}
+	if reason := server.checkLogin(req); reason != nil {
+		rejectLogin(player, *reason)
+		return false
+	}
+	if reason := server.join(player); reason != nil {
+		rejectLogin(player, *reason)
+		return false
+	}
+
+	if threshold := player.server.CompressionThreshold; threshold >= 0 {
+		player.sendPacketImmediatelyUsing(setCompressionID, func(e *proto.PacketEncoder) {
+			e.WriteVar32(int32(threshold))
+		})
+		player.compressionThreshold = threshold
+	}
+	player.sendPacketImmediately(loginSuccessID, LoginSuccess{player})
+	return true
+}
+
+func rejectLogin(player *Player, reason chat.Component) {
+	log.Infof("%s (%s) can't join: %s", player.Username, player.Conn.RemoteAddr(), reason.PlainText())
+	player.sendPacketImmediately(loginDisconnectID, loginDisconnect{reason})
 }
@@ -8,6 +8,8 @@ import (
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,8 +18,13 @@ type Player struct {
 	socketDecoder *proto.PacketDecoder
 	socketEncoder *proto.PacketEncoder
 
+	server        *Server
 	stopAll       context.CancelFunc // stops all goroutines that are spawned for handling this player
+	stopped       <-chan struct{}    // closed once stopAll is called
 	packetsToSend chan []byte
+	// compressionThreshold is the size from which packets are compressed, or -1 before compression is enabled. It's
+	// only changed during login, before packets are sent from other goroutines.
+	compressionThreshold int
 
 	Version  int
 	UUID     uuid.UUID
@@ -29,15 +36,48 @@ type Player struct {
 	OnGround bool
 	Yaw      float32
 	Pitch    float32
+	// moveMu is held while FeetPos, OnGround, Yaw and Pitch change. They only change on the goroutine reading from the
+	// player, which can read them without it, other goroutines need to hold it.
+	moveMu sync.Mutex
 
-	LastKeepAlive time.Time
+	gamemode  Gamemode // only changed before the player joins a world, which is set under view.mu after it
+	inventory inventory
+	// savedData is the player data the player was loaded from, which is saved again with what changed. It's only set
+	// before the player joins a world.
+	savedData map[string]interface{}
+
+	lastKeepAlive int64 // unix nanoseconds, accessed atomically since it's checked while sending packets
+
+	world *worlds.Dimension // guarded by view.mu, since it changes along with the view
+	view  chunkView
+	ticks uint64 // ticks since the player joined, accessed atomically since two worlds tick the player while it changes dimensions
+
+	teleportMu      sync.Mutex
+	pendingTeleport *teleport // the last teleport until the client confirms it
+	lastTeleportID  int32
+}
+
+// LastKeepAlive returns when the client last answered a keep alive
+func (p *Player) LastKeepAlive() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&p.lastKeepAlive))
+}
+
+func (p *Player) keptAlive() {
+	atomic.StoreInt64(&p.lastKeepAlive, time.Now().UnixNano())
 }
 
 func (p *Player) ChunkPos() pstn.Chunk {
-	return pstn.EntityToChunk(p.Pos)
+	return pstn.EntityToChunk(p.FeetPos)
 }
 
 func (p *Player) Disconnect(err error) {
+	select {
+	case <-p.stopped:
+		// Already stopped, e.g. after a kick, so the error is only from the connection being closed
+		p.Conn.Close()
+		return
+	default:
+	}
 	log.Errorf("client '%s' disconnected with error: %v\n",
 		p.Conn.RemoteAddr().String(), err)
 	p.stopAll()
@@ -45,11 +85,17 @@ func (p *Player) Disconnect(err error) {
 }
 
 func (p *Player) readPacket() proto.RecvPacket {
+	if p.compressionThreshold >= 0 {
+		return p.socketDecoder.ReadCompressedPacket()
+	}
 	return p.socketDecoder.ReadPacket()
 }
 
 func (p *Player) writePacket(packet []byte) {
-	// No support for compression
+	if p.compressionThreshold >= 0 {
+		p.socketEncoder.WriteCompressedPacket(packet, p.compressionThreshold)
+		return
+	}
 	p.socketEncoder.WritePacket(packet)
 }
 
@@ -76,22 +122,33 @@ func (p *Player) SendPacket(id proto.PacketID, packet proto.EncodableAsPacket) b
 	}
 }
 
+// queuePacket is like SendPacket, but waits for room in the buffer instead of dropping the packet. It's used for
+// packets the client can't do without, like chunks.
+func (p *Player) queuePacket(id proto.PacketID, packet proto.EncodableAsPacket) {
+	select {
+	case p.packetsToSend <- proto.EncodePacket(id, packet):
+	case <-p.stopped:
+	}
+}
+
 func (p *Player) SendPacketUsing(id proto.PacketID, encodeFunc proto.EncodeFunc) bool {
 	return p.SendPacket(id, proto.NewPacket(encodeFunc))
 }
 
 const defaultSendPacketsBuffered = 128
 
-func newPlayer(conn net.Conn) (*Player, context.Context) {
-	player := Player{}
+func newPlayer(server *Server, conn net.Conn) (*Player, context.Context) {
+	player := Player{server: server, compressionThreshold: -1}
 	player.socketDecoder = proto.NewPacketDecoder(conn)
 	player.socketEncoder = proto.NewEncoder(conn)
 	player.Conn = conn
 
 	player.packetsToSend = make(chan []byte, defaultSendPacketsBuffered)
+	player.view.queued = make(chan struct{}, 1)
 
 	ctx := context.Background()
 	ctx, player.stopAll = context.WithCancel(ctx)
+	player.stopped = ctx.Done()
 	return &player, ctx
 }
 
@@ -105,18 +162,25 @@ func catchPlayerPanic(player *Player) {
 	}
 }
 
-func HandlePlayer(world *worlds.Dimension, conn net.Conn) {
+func HandlePlayer(server *Server, conn net.Conn) {
 	defer conn.Close()
 
-	player, ctx := newPlayer(conn)
+	player, ctx := newPlayer(server, conn)
 	defer catchPlayerPanic(player)
 
+	if isLegacyPing(player) {
+		handleLegacyPing(server, player)
+		return
+	}
 	state := handleHandshake(player)
 	if state == status {
-		handleStatus(player)
+		handleStatus(server, player)
 		return
 	} else if state == login {
-		handleLogin(player)
-		handlePlay(ctx, world, player)
+		defer server.removePlayer(player) // also if the connection fails right after the player joined
+		if !handleLogin(server, player) {
+			return
+		}
+		handlePlay(ctx, server, player)
 	}
 }
@@ -0,0 +1,19 @@
+package net
+
+import (
+	uuid "github.com/satori/go.uuid"
+)
+
+// Permissions decide what players may do, by permission nodes like mc.command.ban
+type Permissions interface {
+	HasPermission(id uuid.UUID, node string) bool
+}
+
+// HasPermission returns true if the player is granted the permission node. Players have no permissions on servers
+// without Permissions.
+func (p *Player) HasPermission(node string) bool {
+	if p.server.Permissions == nil {
+		return false
+	}
+	return p.server.Permissions.HasPermission(p.UUID, node)
+}
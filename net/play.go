@@ -9,18 +9,32 @@ import (
 	"github.com/masp/mcgo/pstn"
 	"github.com/masp/mcgo/worlds"
 	log "github.com/sirupsen/logrus"
+	"sync/atomic"
 	"time"
 )
 
-type gamemode uint8
+type Gamemode uint8
 
 const (
-	survival  gamemode = 0
-	creative  gamemode = 1
-	adventure gamemode = 2
-	spectator gamemode = 3
+	Survival  Gamemode = 0
+	Creative  Gamemode = 1
+	Adventure Gamemode = 2
+	Spectator Gamemode = 3
 )
 
+var gamemodeNames = map[string]Gamemode{
+	"survival":  Survival,
+	"creative":  Creative,
+	"adventure": Adventure,
+	"spectator": Spectator,
+}
+
+// ParseGamemode returns the gamemode with the given name, e.g. creative
+func ParseGamemode(name string) (Gamemode, bool) {
+	mode, ok := gamemodeNames[name]
+	return mode, ok
+}
+
 // IDs as of 1.16.2
 
 // Serverbound
@@ -32,6 +46,10 @@ const (
 	playerPosAndRotID            = 0x13
 	playerRotID                  = 0x14
 	playerMovementID             = 0x15
+	playerDiggingID              = 0x1B
+	heldItemChangeServerboundID  = 0x25
+	creativeInventoryActionID    = 0x28
+	playerBlockPlacementID       = 0x2E
 )
 
 // Clientbound
@@ -50,11 +68,20 @@ const (
 	playerPosAndLookClientboundID = 0x34
 	chunkDataID                   = 0x20
 	updateLightID                 = 0x23
+	blockEntityDataID             = 0x09
+	blockChangeID                 = 0x0B
+	multiBlockChangeID            = 0x3B
+	unloadChunkID                 = 0x1C
+	respawnID                     = 0x39
+	acknowledgeDiggingID          = 0x07
+	windowItemsID                 = 0x13
 )
 
 type JoinGame struct {
 	player *Player
-	mode   gamemode
+	mode   Gamemode
+	server *Server
+	world  *worlds.Dimension
 }
 
 func (j JoinGame) EncodeTo(e *proto.PacketEncoder) {
@@ -63,20 +90,23 @@ func (j JoinGame) EncodeTo(e *proto.PacketEncoder) {
 	e.WriteU8(uint8(j.mode)) // gamemode
 	e.WriteU8(uint8(j.mode)) // previous gamemode
 
-	e.WriteVar32(1) // number of worlds
-	e.WriteString("minecraft:overworld")
+	dimensions := j.server.Worlds.Worlds()
+	e.WriteVar32(int32(len(dimensions))) // number of worlds
+	for _, d := range dimensions {
+		e.WriteString(d.Name)
+	}
 
 	e.WriteNBT(biome.BuildRegistry())
-	e.WriteNBT(biome.OverworldDimension())
-	e.WriteString("minecraft:overworld")
-
-	e.WriteI64(0)      // hashed seed
-	e.WriteVar32(1337) // unused
-	e.WriteVar32(16)   // max view distance
-	e.WriteBool(false) // reduced debug info
-	e.WriteBool(true)  // show respawn screen
-	e.WriteBool(false) // is debug
-	e.WriteBool(false) // is flat
+	e.WriteNBT(j.world.Type.Def())
+	e.WriteString(j.world.Name)
+
+	e.WriteI64(0)                              // hashed seed
+	e.WriteVar32(int32(j.server.MaxPlayers))   // max players, unused
+	e.WriteVar32(int32(j.server.ViewDistance)) // view distance
+	e.WriteBool(false)                         // reduced debug info
+	e.WriteBool(true)                          // show respawn screen
+	e.WriteBool(false)                         // is debug
+	e.WriteBool(false)                         // is flat
 }
 
 type keepAlive struct {
@@ -94,37 +124,67 @@ var (
 func handleSendingPackets(ctx context.Context, player *Player) {
 	defer catchPlayerPanic(player)
 
-	flushTicker := time.NewTicker(time.Second / 20) // Force flush every 50ms for lower latency
-	heartbeatTicker := time.NewTicker(time.Second)
 	for {
 		select {
 		case <-ctx.Done():
-			flushTicker.Stop()
-			heartbeatTicker.Stop()
 			return
-		case <-heartbeatTicker.C:
-			if time.Now().Sub(player.LastKeepAlive) > 30*time.Second {
-				player.Disconnect(ErrTimeout)
-			}
-			player.writePacket(proto.EncodePacket(keepAliveClientboundID, keepAlive{time.Now().Unix()}))
-		case <-flushTicker.C:
-			player.socketEncoder.Flush()
 		case p := <-player.packetsToSend:
+			if p == nil {
+				// Kicked, the disconnect packet was queued before this
+				player.socketEncoder.Flush()
+				player.stopAll()
+				player.Conn.Close()
+				return
+			}
 			player.writePacket(p)
+			// Everything a tick sends is queued at once, so flush once it has all been written
+			if len(player.packetsToSend) == 0 {
+				player.socketEncoder.Flush()
+			}
 		}
 	}
 }
 
-func handlePlay(ctx context.Context, world *worlds.Dimension, player *Player) {
+// keepAliveInterval is how often the player is sent a keep alive, in ticks
+const keepAliveInterval = worlds.TicksPerSecond
+
+// Tick is called by the world every tick
+func (p *Player) Tick() {
+	if atomic.AddUint64(&p.ticks, 1)%keepAliveInterval != 0 {
+		return
+	}
+	if time.Now().Sub(p.LastKeepAlive()) > 30*time.Second {
+		p.Disconnect(ErrTimeout)
+		return
+	}
+	p.SendPacket(keepAliveClientboundID, keepAlive{time.Now().Unix()})
+}
+
+func handlePlay(ctx context.Context, server *Server, player *Player) {
+	world := server.loadPlayer(player)
 	player.EID = 999 // TODO: register entities
-	player.LastKeepAlive = time.Now()
+	player.keptAlive()
 	player.sendPacketImmediately(joinGameID, JoinGame{
 		player: player,
-		mode:   creative,
+		mode:   player.Gamemode(),
+		server: server,
+		world:  world,
 	})
 
+	player.view.mu.Lock()
+	player.world = world
+	player.view.mu.Unlock()
+	defer player.leaveWorld()
+	defer func() {
+		if err := player.SaveData(); err != nil {
+			log.Errorf("failed to save player data of %s: %v", player.Username, err)
+		}
+	}()
 	spawnPlayer(world, player)
+	world.AddViewer(player)
+	world.AddEntity(player)
 	go handleSendingPackets(ctx, player)
+	go player.sendViewPackets(ctx)
 	for {
 		select {
 		case <-ctx.Done():
@@ -139,29 +199,67 @@ func handlePlay(ctx context.Context, world *worlds.Dimension, player *Player) {
 func (p *Player) handlePacket(packet proto.RecvPacket) {
 	switch packet.ID {
 	case keepAliveServerboundID:
-		p.LastKeepAlive = time.Now()
+		p.keptAlive()
+	case chatMessageServerboundID:
+		p.handleChat(packet.ReadString())
 	case teleportConfirmServerboundID:
-		log.Info("TODO: TeleportConfirm packet")
-		// TODO
+		p.confirmTeleport(packet.ReadVar32())
 	case clientSettingsID:
 		log.Info("TODO: ClientSettings packet")
 		// TODO
 	case playerPosID:
-		play.Handle()
+		pos := pstn.Entity{X: packet.ReadFloat64(), Y: packet.ReadFloat64(), Z: packet.ReadFloat64()}
+		p.look(p.Yaw, p.Pitch, packet.ReadBool())
+		p.move(pos)
 	case playerPosAndRotID:
+		pos := pstn.Entity{X: packet.ReadFloat64(), Y: packet.ReadFloat64(), Z: packet.ReadFloat64()}
+		p.look(packet.ReadFloat32(), packet.ReadFloat32(), packet.ReadBool())
+		p.move(pos)
 	case playerRotID:
+		p.look(packet.ReadFloat32(), packet.ReadFloat32(), packet.ReadBool())
 	case playerMovementID:
+		p.look(p.Yaw, p.Pitch, packet.ReadBool())
+	case playerDiggingID:
+		status := packet.ReadVar32()
+		p.handleDigging(status, packet.ReadPosition())
+	case playerBlockPlacementID:
+		hand := packet.ReadVar32()
+		pos := packet.ReadPosition()
+		p.handlePlacement(hand == 1, pos, packet.ReadVar32())
+	case heldItemChangeServerboundID:
+		p.selectSlot(packet.ReadI16())
+	case creativeInventoryActionID:
+		slot := packet.ReadI16()
+		p.setCreativeSlot(slot, readSlot(&packet))
 
 	default:
 		log.Infof("Received unknown packet 0x%2x, ignoring", packet.ID)
 	}
 }
 
+// move handles the client moving the player, which is ignored while a teleport is pending since the client sent it
+// from where it was before
+func (p *Player) move(pos pstn.Entity) {
+	if p.teleporting() {
+		return
+	}
+	p.moveMu.Lock()
+	p.FeetPos = pos
+	p.moveMu.Unlock()
+	p.updateView()
+}
+
+// look changes where the player looks and if they are on the ground
+func (p *Player) look(yaw, pitch float32, onGround bool) {
+	p.moveMu.Lock()
+	defer p.moveMu.Unlock()
+	p.Yaw, p.Pitch, p.OnGround = yaw, pitch, onGround
+}
+
+// spawnPlayer sends the player what they need to start playing at FeetPos
 func spawnPlayer(world *worlds.Dimension, p *Player) {
-	p.Pos = pstn.BlockToEntity(world.Spawn)
-	// TODO: Send held item
 	p.sendPacketImmediatelyUsing(heldItemChangeID, func(e *proto.PacketEncoder) {
-		e.WriteI8(1)
+		e.WriteI8(int8(p.HeldSlot()))
 	})
 
 	// TODO: Send recipes
@@ -217,10 +315,10 @@ func spawnPlayer(world *worlds.Dimension, p *Player) {
 		e.WriteVar32(1) // 1 player
 		e.WriteUUID(p.UUID)
 		e.WriteString(p.Username)
-		e.WriteVar32(0)               // no properties
-		e.WriteVar32(int32(creative)) // gamemode
-		e.WriteVar32(0)               // ping
-		e.WriteBool(false)            // has display name
+		e.WriteVar32(0)                   // no properties
+		e.WriteVar32(int32(p.Gamemode())) // gamemode
+		e.WriteVar32(0)                   // ping
+		e.WriteBool(false)                // has display name
 	})
 
 	// TODO: Player info - update latency
@@ -237,35 +335,64 @@ func spawnPlayer(world *worlds.Dimension, p *Player) {
 		e.WriteVar32(p.ChunkPos().Z)
 	})
 
-	sendChunks(world, p)
+	sendInitialChunks(p)
 
 	p.sendPacketImmediatelyUsing(spawnPositionID, func(e *proto.PacketEncoder) {
 		e.WritePosition(world.Spawn)
 	})
 
-	// TODO: Player position and look
-	p.sendPacketImmediatelyUsing(playerPosAndLookClientboundID, func(e *proto.PacketEncoder) {
-		e.WriteFloat64(p.Pos.X) // X
-		e.WriteFloat64(p.Pos.Y) // Y
-		e.WriteFloat64(p.Pos.Z) // Z
-		e.WriteFloat32(0)       // Yaw
-		e.WriteFloat32(0)       // Pitch
-		e.WriteI8(0)
-		e.WriteVar32(0)
+	p.sendInventory()
+	p.sendPacketImmediately(playerPosAndLookClientboundID, playerPosAndLook{
+		Pos:    p.FeetPos,
+		Yaw:    p.Yaw,
+		Pitch:  p.Pitch,
+		Rotate: true,
+		ID:     p.expectTeleport(p.FeetPos),
 	})
 }
 
-const ViewingDistance = 8
-
-func sendChunks(world *worlds.Dimension, p *Player) {
-	center := p.ChunkPos()
-	for x := center.X - ViewingDistance; x <= center.X+ViewingDistance; x++ {
-		for z := center.Z - ViewingDistance; z <= center.Z+ViewingDistance; z++ {
-			// Send chunk data
-			chunk := world.ChunkAt(pstn.Chunk{X: x, Z: z})
-			p.sendPacketImmediately(chunkDataID, chunk)
-			// TODO: Send proper chunk lighting
-			p.sendPacketImmediately(updateLightID, chunks.ChunkLightingPacket{Chunk: chunk})
+// SendBlockEntity tells the player about a new or changed block entity. Block entities that the client doesn't need
+// updates for (like chests) are skipped, since they are already sent along with the chunk.
+func (p *Player) SendBlockEntity(be *chunks.BlockEntity) {
+	p.view.mu.Lock()
+	defer p.view.mu.Unlock()
+	p.queueBlockEntity(be)
+}
+
+// queueBlockEntity queues the block entity like SendBlockEntity. view.mu must be held.
+func (p *Player) queueBlockEntity(be *chunks.BlockEntity) {
+	if _, ok := be.UpdateAction(); ok {
+		p.queueViewPacket(blockEntityDataID, chunks.BlockEntityDataPacket{Entity: be})
+	}
+}
+
+// SendChunkChanges sends the blocks that changed in a chunk, using a single Block Change for sections with only one
+// change and Multi Block Change otherwise, followed by the block entities that changed. If the whole chunk changed,
+// it's resent instead. The packets go through the view's queue, so they are never dropped or sent before the chunk,
+// and the tick doesn't wait for a player with a full buffer.
+func (p *Player) SendChunkChanges(changes *chunks.ColumnChanges) {
+	p.view.mu.Lock()
+	defer p.view.mu.Unlock()
+	if changes.Full {
+		p.queueViewPacket(chunkDataID, changes.Chunk)
+		p.queueViewPacket(updateLightID, chunks.ChunkLightingPacket{Chunk: changes.Chunk})
+		return
+	}
+
+	for sectionY, changed := range changes.Sections {
+		switch len(changed) {
+		case 0:
+		case 1:
+			p.queueViewPacket(blockChangeID, chunks.NewBlockChangePacket(changes.Chunk, sectionY, changed[0]))
+		default:
+			p.queueViewPacket(multiBlockChangeID, chunks.MultiBlockChangePacket{
+				Chunk:    changes.Chunk,
+				SectionY: sectionY,
+				Blocks:   changed,
+			})
 		}
 	}
+	for _, be := range changes.BlockEntities {
+		p.queueBlockEntity(be)
+	}
 }
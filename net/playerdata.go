@@ -0,0 +1,177 @@
+package net
+
+import (
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/items"
+	"github.com/masp/mcgo/pstn"
+	"github.com/masp/mcgo/worlds"
+	log "github.com/sirupsen/logrus"
+	"os"
+)
+
+// Gamemode returns the gamemode of the player
+func (p *Player) Gamemode() Gamemode {
+	return p.gamemode
+}
+
+// dataSlot returns the slot of player data for a slot of the inventory window, false for crafting slots which
+// aren't kept
+func dataSlot(window int) (int8, bool) {
+	switch {
+	case window >= HotbarStart && window < HotbarStart+HotbarSize:
+		return int8(anvil.HotbarSlot + window - HotbarStart), true
+	case window >= anvil.MainSlot && window < HotbarStart:
+		return int8(window), true
+	case window >= 5 && window <= 8: // head to feet
+		return int8(anvil.ArmorSlot + 8 - window), true
+	case window == OffhandSlot:
+		return anvil.OffhandSlot, true
+	}
+	return 0, false
+}
+
+// windowSlot is the reverse of dataSlot
+func windowSlot(data int8) (int, bool) {
+	switch {
+	case data >= anvil.HotbarSlot && data < anvil.HotbarSlot+HotbarSize:
+		return HotbarStart + int(data-anvil.HotbarSlot), true
+	case data >= anvil.MainSlot && data < HotbarStart:
+		return int(data), true
+	case data >= anvil.ArmorSlot && data < anvil.ArmorSlot+4:
+		return 8 - int(data-anvil.ArmorSlot), true
+	case data == anvil.OffhandSlot:
+		return OffhandSlot, true
+	}
+	return 0, false
+}
+
+// data returns what is saved about the player. It can be called from any goroutine once the player is in a world.
+func (p *Player) data() anvil.PlayerData {
+	world := p.World()
+	d := anvil.PlayerData{
+		Dimension:        world.Type.Name(),
+		World:            world.Name,
+		GameType:         int32(p.Gamemode()),
+		SelectedItemSlot: int32(p.HeldSlot()),
+		Raw:              p.savedData,
+	}
+	p.moveMu.Lock()
+	d.Pos, d.Yaw, d.Pitch, d.OnGround = p.FeetPos, p.Yaw, p.Pitch, p.OnGround
+	p.moveMu.Unlock()
+	p.inventory.mu.Lock()
+	slots, kept := p.inventory.slots, p.inventory.kept
+	p.inventory.mu.Unlock()
+	used := make(map[int8]bool)
+	for i, s := range slots {
+		slot, ok := dataSlot(i)
+		if !ok || s.Empty() {
+			continue
+		}
+		item, ok := items.ByID(s.Item)
+		if !ok {
+			continue
+		}
+		d.Inventory = append(d.Inventory, anvil.PlayerItem{Slot: slot, ID: item.Name, Count: s.Count, Tag: s.NBT})
+		used[slot] = true
+	}
+	for _, it := range kept {
+		if !used[it.Slot] {
+			d.Inventory = append(d.Inventory, it)
+		}
+	}
+	return d
+}
+
+// restore puts the player back how they were saved in d
+func (p *Player) restore(d anvil.PlayerData) {
+	p.moveMu.Lock()
+	p.FeetPos = d.Pos
+	p.Yaw = d.Yaw
+	p.Pitch = d.Pitch
+	p.OnGround = d.OnGround
+	p.moveMu.Unlock()
+	if mode := Gamemode(d.GameType); mode <= Spectator {
+		p.gamemode = mode
+	}
+	p.savedData = d.Raw
+
+	p.inventory.mu.Lock()
+	defer p.inventory.mu.Unlock()
+	if d.SelectedItemSlot >= 0 && d.SelectedItemSlot < HotbarSize {
+		p.inventory.held = int(d.SelectedItemSlot)
+	}
+	for _, it := range d.Inventory {
+		slot, ok := windowSlot(it.Slot)
+		item, known := items.ByName(it.ID)
+		if !ok || !known {
+			p.inventory.kept = append(p.inventory.kept, it)
+			continue
+		}
+		p.inventory.slots[slot] = Slot{Item: item.ID, Count: it.Count, NBT: it.Tag}
+	}
+}
+
+// savedWorld returns the world player data is from. Worlds are found by name, or else by dimension for data that
+// vanilla saved. If neither is hosted, false is returned.
+func (s *Server) savedWorld(d anvil.PlayerData) (*worlds.Dimension, bool) {
+	if d.World != "" {
+		if world := s.Worlds.World(d.World); world != nil {
+			return world, true
+		}
+	}
+	for _, world := range s.Worlds.Worlds() {
+		if world.Type.Name() == d.Dimension {
+			return world, true
+		}
+	}
+	return nil, false
+}
+
+// loadPlayer returns the world the player joins in and puts them where they were when they last left. New players
+// start at the spawn of the default world with the gamemode of the server.
+func (s *Server) loadPlayer(p *Player) *worlds.Dimension {
+	world := s.spawnDimension()
+	p.gamemode = s.Gamemode
+	p.FeetPos = pstn.BlockToEntity(world.Spawn)
+	if s.PlayerDataDir == "" {
+		return world
+	}
+
+	d, err := anvil.ReadPlayerData(s.PlayerDataDir, p.UUID)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("failed to load player data of %s: %v", p.Username, err)
+		}
+		return world
+	}
+	saved, ok := s.savedWorld(d)
+	p.restore(d)
+	if !ok {
+		p.FeetPos = pstn.BlockToEntity(world.Spawn) // the world they were in is gone
+		return world
+	}
+	return saved
+}
+
+// SaveData writes the position, gamemode and inventory of the player to the player data of the server. It does
+// nothing on servers without PlayerDataDir.
+func (p *Player) SaveData() error {
+	if p.server.PlayerDataDir == "" || p.World() == nil {
+		return nil
+	}
+	return anvil.WritePlayerData(p.server.PlayerDataDir, p.UUID, p.data())
+}
+
+// SavePlayers saves the data of every player that is online
+func (s *Server) SavePlayers() error {
+	var firstErr error
+	for _, p := range s.Players() {
+		if err := p.SaveData(); err != nil {
+			log.Errorf("failed to save player data of %s: %v", p.Username, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,78 @@
+package net
+
+import (
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/items"
+	"github.com/masp/mcgo/pstn"
+	"reflect"
+	"testing"
+)
+
+func TestDataSlot(t *testing.T) {
+	seen := make(map[int8]bool)
+	for window := 0; window < InventorySize; window++ {
+		data, ok := dataSlot(window)
+		if crafting := window < 5; ok == crafting {
+			t.Errorf("dataSlot(%d) ok = %v, want %v", window, ok, !crafting)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if seen[data] {
+			t.Errorf("dataSlot(%d) = %d, which another slot has too", window, data)
+		}
+		seen[data] = true
+		if got, ok := windowSlot(data); !ok || got != window {
+			t.Errorf("windowSlot(%d) = %d, %v, want %d", data, got, ok, window)
+		}
+	}
+	if _, ok := windowSlot(anvil.OffhandSlot - 1); ok {
+		t.Errorf("windowSlot(%d) ok = true for a slot that isn't saved", anvil.OffhandSlot-1)
+	}
+}
+
+func TestPlayer_restore(t *testing.T) {
+	p := newTestPlayer(t, Creative, nil)
+	p.Yaw, p.Pitch, p.OnGround = 90, -15.5, true
+	p.inventory.held = 4
+	p.inventory.slots[HotbarStart+4] = Slot{Item: items.Stone, Count: 64}
+	p.inventory.slots[5] = Slot{Item: items.OakLog, Count: 1, NBT: map[string]interface{}{"Damage": int32(3)}}
+	p.inventory.slots[OffhandSlot] = Slot{Item: items.Glass, Count: 12}
+	p.inventory.slots[1] = Slot{Item: items.Dirt, Count: 1} // crafting isn't saved
+	saved := p.data()
+
+	restored := newTestPlayer(t, Survival, nil)
+	restored.restore(saved)
+	if restored.FeetPos != (pstn.Entity{X: 0.5, Y: 10, Z: 0.5}) || restored.Yaw != 90 || restored.Pitch != -15.5 ||
+		!restored.OnGround || restored.Gamemode() != Creative {
+		t.Errorf("restored player at %v looking %v %v in gamemode %d, want how they were saved",
+			restored.FeetPos, restored.Yaw, restored.Pitch, restored.Gamemode())
+	}
+	if got := restored.data(); !reflect.DeepEqual(got, saved) {
+		t.Errorf("data() after restore() = %+v, want %+v", got, saved)
+	}
+	if !restored.inventory.slots[1].Empty() {
+		t.Errorf("crafting slot was restored")
+	}
+}
+
+func TestPlayer_restoreKeepsUnknown(t *testing.T) {
+	modded := anvil.PlayerItem{Slot: anvil.MainSlot, ID: "othermod:crystal", Count: 3,
+		Tag: map[string]interface{}{"Charge": int32(7)}}
+	replaced := anvil.PlayerItem{Slot: anvil.MainSlot + 1, ID: "othermod:wand", Count: 1}
+	oddSlot := anvil.PlayerItem{Slot: 80, ID: "minecraft:stone", Count: 2}
+	raw := map[string]interface{}{"Health": float32(7.5), "XpLevel": int32(12)}
+	p := newTestPlayer(t, Survival, nil)
+	p.restore(anvil.PlayerData{Inventory: []anvil.PlayerItem{modded, replaced, oddSlot}, Raw: raw})
+	p.inventory.slots[anvil.MainSlot+1] = Slot{Item: items.Dirt, Count: 5}
+
+	got := p.data()
+	want := []anvil.PlayerItem{{Slot: anvil.MainSlot + 1, ID: "minecraft:dirt", Count: 5}, modded, oddSlot}
+	if !reflect.DeepEqual(got.Inventory, want) {
+		t.Errorf("data().Inventory = %+v, want %+v", got.Inventory, want)
+	}
+	if !reflect.DeepEqual(got.Raw, raw) {
+		t.Errorf("data().Raw = %v, want %v", got.Raw, raw)
+	}
+}
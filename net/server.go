@@ -0,0 +1,109 @@
+package net
+
+import (
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/worlds"
+	"net"
+	"sync"
+)
+
+const (
+	ProtocolVersion = 751
+	VersionName     = "1.16.2"
+)
+
+// Server is the state that every connection shares
+type Server struct {
+	Worlds *worlds.Manager
+
+	// MOTD is shown below the name of the server in the server list
+	MOTD       chat.Component
+	MaxPlayers int
+	// Favicon is the icon of the server in the server list, see LoadFavicon
+	Favicon string
+	// StatusHook can change what the server list shows, depending on who is asking. It's called from the goroutine of
+	// the connection.
+	StatusHook func(addr net.Addr, resp *StatusResponse)
+	// ViewDistance is how many chunks around them players see in each direction
+	ViewDistance int
+	// CompressionThreshold is the size from which packets are compressed, compression is disabled if it's negative
+	CompressionThreshold int
+	// Gamemode is the gamemode new players join in
+	Gamemode Gamemode
+	// PlayerDataDir is the world directory where players are saved when they leave, so they come back where they left.
+	// Players aren't saved if it's empty.
+	PlayerDataDir string
+
+	// Bans keeps banned players and addresses from joining, nobody is banned if it's nil
+	Bans BanList
+	// Whitelist only lets the players on it join, everyone can join if it's nil
+	Whitelist Whitelist
+	// Ops may join without being on the whitelist, and past MaxPlayers if they bypass the player limit. Nobody is an
+	// operator if it's nil.
+	Ops OpList
+	// PreLogin is asked about every player the server would let in, and can still disconnect them
+	PreLogin PreLoginHook
+	// Permissions decide what players may do, see Player.HasPermission
+	Permissions Permissions
+	// OnCommand runs a command a player typed in chat, without the leading slash. It's called from the goroutine of the
+	// player, so it mustn't wait on the player.
+	OnCommand func(p *Player, line string)
+
+	playersMu sync.Mutex
+	players   map[*Player]struct{}
+	sessions  sync.WaitGroup // players that joined and haven't been saved and removed yet
+	closed    bool           // set by Shutdown, guarded by playersMu
+
+	listenersMu sync.Mutex
+	listeners   map[net.Listener]struct{}
+}
+
+// NewServer creates a server where players can be in any of the worlds of the manager. Players join its default world.
+// The other settings are the defaults of vanilla and can be changed before the first player connects.
+func NewServer(manager *worlds.Manager) *Server {
+	return &Server{
+		Worlds:               manager,
+		MOTD:                 chat.Text("A Minecraft Server"),
+		MaxPlayers:           20,
+		ViewDistance:         10,
+		CompressionThreshold: 256,
+		Gamemode:             Survival,
+		players:              make(map[*Player]struct{}),
+		listeners:            make(map[net.Listener]struct{}),
+	}
+}
+
+// Players returns every player that is online
+func (s *Server) Players() []*Player {
+	s.playersMu.Lock()
+	defer s.playersMu.Unlock()
+	players := make([]*Player, 0, len(s.players))
+	for p := range s.players {
+		players = append(players, p)
+	}
+	return players
+}
+
+func (s *Server) PlayerCount() int {
+	s.playersMu.Lock()
+	defer s.playersMu.Unlock()
+	return len(s.players)
+}
+
+// removePlayer removes the player from the online players, if they joined
+func (s *Server) removePlayer(p *Player) {
+	s.playersMu.Lock()
+	defer s.playersMu.Unlock()
+	if _, ok := s.players[p]; ok {
+		delete(s.players, p)
+		s.sessions.Done()
+	}
+}
+
+func (s *Server) spawnDimension() *worlds.Dimension {
+	world := s.Worlds.Default()
+	if world == nil {
+		panic("net: the server has no world to spawn players in")
+	}
+	return world
+}
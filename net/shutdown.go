@@ -0,0 +1,85 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"github.com/masp/mcgo/chat"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"time"
+)
+
+// ErrServerClosed is returned by Serve once the server is shut down
+var ErrServerClosed = errors.New("server closed")
+
+// Serve accepts connections on ln and handles each on its own goroutine, until the server is shut down
+func (s *Server) Serve(ln net.Listener) error {
+	s.listenersMu.Lock()
+	s.listeners[ln] = struct{}{}
+	s.listenersMu.Unlock()
+	defer func() {
+		s.listenersMu.Lock()
+		delete(s.listeners, ln)
+		s.listenersMu.Unlock()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				log.Errorf("failed to accept connection: %v", err)
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go HandlePlayer(s, conn)
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.playersMu.Lock()
+	defer s.playersMu.Unlock()
+	return s.closed
+}
+
+// Shutdown stops accepting connections and kicks every player with reason. It waits until each player got every packet
+// that was queued for them and their data is saved, or until ctx is done, after which the remaining connections are
+// closed without waiting. The worlds aren't saved, since they don't belong to the server.
+func (s *Server) Shutdown(ctx context.Context, reason chat.Component) error {
+	s.playersMu.Lock()
+	s.closed = true
+	s.playersMu.Unlock()
+
+	s.listenersMu.Lock()
+	for ln := range s.listeners {
+		ln.Close()
+	}
+	s.listenersMu.Unlock()
+
+	players := s.Players()
+	for _, p := range players {
+		go p.Kick(reason) // kicks wait for room in the send queue of players that are lagging
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.sessions.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, p := range s.Players() {
+			p.stopAll()
+			p.Conn.Close()
+		}
+		<-done // closing the connection ends the session, which saves the player
+		return ctx.Err()
+	}
+}
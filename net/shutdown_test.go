@@ -0,0 +1,180 @@
+package net
+
+import (
+	"context"
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/worlds"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newShutdownServer returns a server without compression that saves players in a temporary directory
+func newShutdownServer(t *testing.T) *Server {
+	dir, err := ioutil.TempDir("", "shutdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := worlds.NewManager("")
+	if _, err := manager.Create(worlds.WorldSettings{Name: "world"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		manager.Close()
+		os.RemoveAll(dir)
+	})
+
+	server := NewServer(manager)
+	server.CompressionThreshold = -1
+	server.ViewDistance = 2
+	server.PlayerDataDir = dir
+	return server
+}
+
+// joinAs logs in on conn like a client would, and returns the decoder for the packets of the play state
+func joinAs(t *testing.T, conn net.Conn, username string) *proto.PacketDecoder {
+	t.Helper()
+	e := proto.NewEncoder(conn)
+	e.WritePacket(proto.EncodePacket(handshakeID, proto.NewPacket(func(e *proto.PacketEncoder) {
+		e.WriteVar32(ProtocolVersion)
+		e.WriteString("localhost")
+		e.WriteU16(25565)
+		e.WriteVar32(int32(login))
+	})))
+	e.WritePacket(proto.EncodePacket(loginStartID, proto.NewPacket(func(e *proto.PacketEncoder) {
+		e.WriteString(username)
+	})))
+	e.Flush()
+
+	d := proto.NewPacketDecoder(conn)
+	if p := d.ReadPacket(); p.ID != loginSuccessID {
+		t.Fatalf("got packet %d during login, want login success", p.ID)
+	}
+	return d
+}
+
+// received is what a client read until the server closed the connection
+type received struct {
+	keepAlives []int64 // the IDs of keep alives, in the order they arrived
+	reason     string  // the JSON of the disconnect packet, empty if there was none
+	afterKick  int     // how many packets arrived after the disconnect packet
+}
+
+// readUntilClosed reads packets from d until the connection is closed
+func readUntilClosed(d *proto.PacketDecoder) <-chan received {
+	done := make(chan received, 1)
+	go func() {
+		var r received
+		defer func() {
+			recover() // reading the closed connection
+			done <- r
+		}()
+		for {
+			p := d.ReadPacket()
+			switch {
+			case r.reason != "":
+				r.afterKick++
+			case p.ID == keepAliveClientboundID:
+				r.keepAlives = append(r.keepAlives, p.ReadI64())
+			case p.ID == playDisconnectID:
+				r.reason = p.ReadString()
+			}
+		}
+	}()
+	return done
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	server := newShutdownServer(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := readUntilClosed(joinAs(t, conn, "Notch"))
+
+	// packets queued before the shutdown are sent before the player is kicked
+	player := server.Players()[0]
+	const queued = 100
+	for i := 0; i < queued; i++ {
+		player.queuePacket(keepAliveClientboundID, keepAlive{int64(i)})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx, chat.Text("Restarting")); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	got := <-client
+	var ids []int64
+	for _, id := range got.keepAlives {
+		if id < queued { // not the ones sent every second
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) != queued || ids[0] != 0 || ids[queued-1] != queued-1 {
+		t.Errorf("client got keep alives %v before the kick, want 0 to %d", ids, queued-1)
+	}
+	if !strings.Contains(got.reason, "Restarting") || got.afterKick != 0 {
+		t.Errorf("client was kicked with %q and got %d packets after, want the reason as the last packet",
+			got.reason, got.afterKick)
+	}
+	if _, err := anvil.ReadPlayerData(server.PlayerDataDir, OfflineUUID("Notch")); err != nil {
+		t.Errorf("player data wasn't saved: %v", err)
+	}
+	if n := server.PlayerCount(); n != 0 {
+		t.Errorf("PlayerCount() after Shutdown() = %d, want 0", n)
+	}
+
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Serve() error = %v, want ErrServerClosed", err)
+	}
+	if conn, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		conn.Close()
+		t.Errorf("server still accepts connections after Shutdown()")
+	}
+	if msg := server.join(&Player{}); msg == nil || msg.PlainText() != ShutdownMessage {
+		t.Errorf("join() after Shutdown() = %v, want %q", msg, ShutdownMessage)
+	}
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	server := newShutdownServer(t)
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go HandlePlayer(server, serverConn)
+
+	// the client reads the join game packet and nothing after, so the server can't send the kick
+	d := joinAs(t, clientConn, "Notch")
+	if p := d.ReadPacket(); p.ID != joinGameID {
+		t.Fatalf("got packet %d after login, want join game", p.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx, chat.Text("Restarting")); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown() error = %v, want context.DeadlineExceeded", err)
+	}
+	if n := server.PlayerCount(); n != 0 {
+		t.Errorf("PlayerCount() after Shutdown() = %d, want 0", n)
+	}
+	if _, err := anvil.ReadPlayerData(server.PlayerDataDir, OfflineUUID("Notch")); err != nil {
+		t.Errorf("player data wasn't saved: %v", err)
+	}
+	clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := ioutil.ReadAll(clientConn); err != nil {
+		t.Errorf("reading the connection after Shutdown() error = %v, want it closed", err)
+	}
+}
@@ -1,20 +1,47 @@
 package net
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/masp/mcgo/chat"
 	"github.com/masp/mcgo/proto"
+	uuid "github.com/satori/go.uuid"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"net"
 )
 
+// maxStatusSample is how many of the online players are listed in the server list, like vanilla
+const maxStatusSample = 12
+
+// faviconSize is the width and height a favicon must have
+const faviconSize = 64
+
 type StatusResponse struct {
 	Version       int
 	VersionName   string
 	MaxPlayers    int
 	OnlinePlayers int
-	Motd          string
+	Sample        []PlayerSample // shown when hovering over the player count
+	Description   chat.Component
+	Favicon       string // PNG image as a data URI, see LoadFavicon
+}
+
+// PlayerSample is a player listed in the server list
+type PlayerSample struct {
+	Name string    `json:"name"`
+	ID   uuid.UUID `json:"id"`
 }
 
 func (s StatusResponse) EncodeTo(e *proto.PacketEncoder) {
+	sample := s.Sample
+	if sample == nil {
+		sample = []PlayerSample{}
+	}
 	resp := map[string]interface{}{
 		"version": map[string]interface{}{
 			"name":     s.VersionName,
@@ -23,11 +50,12 @@ func (s StatusResponse) EncodeTo(e *proto.PacketEncoder) {
 		"players": map[string]interface{}{
 			"max":    s.MaxPlayers,
 			"online": s.OnlinePlayers,
-			"sample": []string{},
-		},
-		"description": map[string]string{
-			"text": s.Motd,
+			"sample": sample,
 		},
+		"description": s.Description,
+	}
+	if s.Favicon != "" {
+		resp["favicon"] = s.Favicon
 	}
 
 	str, err := json.Marshal(resp)
@@ -37,6 +65,49 @@ func (s StatusResponse) EncodeTo(e *proto.PacketEncoder) {
 	e.WriteString(string(str))
 }
 
+// LoadFavicon reads a PNG image of 64x64 pixels and returns it as a data URI for StatusResponse.Favicon
+func LoadFavicon(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	img, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("favicon: %w", err)
+	}
+	if img.Width != faviconSize || img.Height != faviconSize {
+		return "", fmt.Errorf("favicon: must be %dx%d pixels, is %dx%d", faviconSize, faviconSize, img.Width, img.Height)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Status returns what the server list shows to a client at addr. The status hook of the server can change it.
+func (s *Server) Status(addr net.Addr) StatusResponse {
+	players := s.Players()
+	rand.Shuffle(len(players), func(i, j int) { players[i], players[j] = players[j], players[i] })
+	if len(players) > maxStatusSample {
+		players = players[:maxStatusSample]
+	}
+	sample := make([]PlayerSample, len(players))
+	for i, p := range players {
+		sample[i] = PlayerSample{Name: p.Username, ID: p.UUID}
+	}
+
+	resp := StatusResponse{
+		Version:       ProtocolVersion,
+		VersionName:   VersionName,
+		MaxPlayers:    s.MaxPlayers,
+		OnlinePlayers: s.PlayerCount(),
+		Sample:        sample,
+		Description:   s.MOTD,
+		Favicon:       s.Favicon,
+	}
+	if s.StatusHook != nil {
+		s.StatusHook(addr, &resp)
+	}
+	return resp
+}
+
 type Pong struct {
 	Payload int64
 }
@@ -50,20 +121,13 @@ const (
 	PingID    = 0x01
 )
 
-func handleStatus(player *Player) {
+func handleStatus(server *Server, player *Player) {
 	req := player.readPacket()
 	if req.ID != RequestID {
 		panic(errors.New("invalid status packet: expected status request"))
 	}
 
-	resp := StatusResponse{
-		Version:       751,
-		VersionName:   "1.16.2",
-		MaxPlayers:    1337,
-		OnlinePlayers: 0,
-		Motd:          "What a cool server!",
-	}
-	player.sendPacketImmediately(0x00, resp)
+	player.sendPacketImmediately(0x00, server.Status(player.Conn.RemoteAddr()))
 
 	req = player.readPacket()
 	if req.ID == PingID {
@@ -0,0 +1,129 @@
+package net
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/worlds"
+	uuid "github.com/satori/go.uuid"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// statusJSON encodes the response like it's sent to the client and decodes the JSON in it
+func statusJSON(t *testing.T, resp StatusResponse) map[string]interface{} {
+	t.Helper()
+	var b bytes.Buffer
+	e := proto.NewEncoder(&b)
+	resp.EncodeTo(e)
+	e.Flush()
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(proto.NewPacketDecoder(&b).ReadString()), &decoded); err != nil {
+		t.Fatalf("status response isn't JSON: %v", err)
+	}
+	return decoded
+}
+
+func TestServer_Status(t *testing.T) {
+	server := NewServer(worlds.NewManager(""))
+	server.MOTD = chat.Text("hello")
+	for i := 0; i < maxStatusSample+3; i++ {
+		name := "player" + strconv.Itoa(i)
+		server.players[&Player{Username: name, UUID: OfflineUUID(name)}] = struct{}{}
+	}
+
+	resp := server.Status(nil)
+	if resp.OnlinePlayers != maxStatusSample+3 || resp.MaxPlayers != 20 {
+		t.Errorf("Status() has %d of %d players, want %d of 20", resp.OnlinePlayers, resp.MaxPlayers, maxStatusSample+3)
+	}
+	if len(resp.Sample) != maxStatusSample {
+		t.Errorf("Status() samples %d players, want %d", len(resp.Sample), maxStatusSample)
+	}
+	for _, s := range resp.Sample {
+		if s.ID != OfflineUUID(s.Name) {
+			t.Errorf("sample of %s has ID %v", s.Name, s.ID)
+		}
+	}
+
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)}
+	server.StatusHook = func(from net.Addr, resp *StatusResponse) {
+		if from == addr {
+			resp.Description = chat.Text("hook")
+		}
+	}
+	if got := server.Status(addr).Description.PlainText(); got != "hook" {
+		t.Errorf("Status() description = %q, want the one of the hook", got)
+	}
+}
+
+func TestStatusResponse_EncodeTo(t *testing.T) {
+	resp := StatusResponse{
+		Version:       ProtocolVersion,
+		VersionName:   VersionName,
+		MaxPlayers:    20,
+		OnlinePlayers: 1,
+		Description:   chat.Text("hello"),
+	}
+	got := statusJSON(t, resp)
+	if sample, ok := got["players"].(map[string]interface{})["sample"].([]interface{}); !ok || len(sample) != 0 {
+		t.Errorf("sample without players = %v, want []", got["players"])
+	}
+	if _, ok := got["favicon"]; ok {
+		t.Errorf("favicon was sent without one")
+	}
+	if got["version"].(map[string]interface{})["protocol"] != float64(ProtocolVersion) {
+		t.Errorf("version = %v, want protocol %d", got["version"], ProtocolVersion)
+	}
+
+	id := uuid.FromStringOrNil("069a79f4-44e9-4726-a5be-fca90e38aaf5")
+	resp.Sample = []PlayerSample{{Name: "Notch", ID: id}}
+	resp.Favicon = "data:image/png;base64,AAAA"
+	got = statusJSON(t, resp)
+	sample := got["players"].(map[string]interface{})["sample"].([]interface{})
+	want := map[string]interface{}{"name": "Notch", "id": id.String()}
+	if len(sample) != 1 || !reflect.DeepEqual(sample[0], want) {
+		t.Errorf("sample = %v, want %v", sample, want)
+	}
+	if got["favicon"] != resp.Favicon {
+		t.Errorf("favicon = %v, want %s", got["favicon"], resp.Favicon)
+	}
+}
+
+func TestLoadFavicon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "favicon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writePNG := func(name string, size int) string {
+		var b bytes.Buffer
+		if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, size, size))); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	favicon, err := LoadFavicon(writePNG("icon.png", faviconSize))
+	if err != nil || !strings.HasPrefix(favicon, "data:image/png;base64,") {
+		t.Errorf("LoadFavicon() = %.30q, %v, want a PNG data URI", favicon, err)
+	}
+	if _, err := LoadFavicon(writePNG("small.png", 32)); err == nil {
+		t.Errorf("LoadFavicon() of a 32x32 image error = nil")
+	}
+	if _, err := LoadFavicon(filepath.Join(dir, "missing.png")); err == nil {
+		t.Errorf("LoadFavicon() of a missing file error = nil")
+	}
+}
@@ -0,0 +1,141 @@
+package net
+
+import (
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	"github.com/masp/mcgo/worlds"
+	"sync/atomic"
+)
+
+// teleport is a move of the player that the client hasn't confirmed yet
+type teleport struct {
+	id  int32
+	pos pstn.Entity
+}
+
+// playerPosAndLook (Player Position And Look) moves the player on the client, which answers with Teleport Confirm
+type playerPosAndLook struct {
+	Pos        pstn.Entity
+	Yaw, Pitch float32
+	Rotate     bool // the player keeps looking where they were if it isn't set
+	ID         int32
+}
+
+func (p playerPosAndLook) EncodeTo(e *proto.PacketEncoder) {
+	e.WriteFloat64(p.Pos.X)
+	e.WriteFloat64(p.Pos.Y)
+	e.WriteFloat64(p.Pos.Z)
+	e.WriteFloat32(p.Yaw)
+	e.WriteFloat32(p.Pitch)
+	if p.Rotate {
+		e.WriteI8(0) // all absolute
+	} else {
+		e.WriteI8(0x08 | 0x10) // yaw and pitch are relative, so adding 0 keeps them
+	}
+	e.WriteVar32(p.ID)
+}
+
+// respawn (Respawn) puts the player in another dimension
+type respawn struct {
+	world *worlds.Dimension
+	mode  Gamemode
+}
+
+func (r respawn) EncodeTo(e *proto.PacketEncoder) {
+	e.WriteNBT(r.world.Type.Def())
+	e.WriteString(r.world.Name)
+	e.WriteI64(0) // hashed seed
+	e.WriteU8(uint8(r.mode))
+	e.WriteU8(uint8(r.mode)) // previous gamemode
+	e.WriteBool(false)       // is debug
+	e.WriteBool(false)       // is flat
+	e.WriteBool(true)        // copy metadata
+}
+
+// expectTeleport records that the player is being moved to pos and returns the ID of the teleport. Until the client
+// confirms it, the moves it sends are from before the teleport and are ignored.
+func (p *Player) expectTeleport(pos pstn.Entity) int32 {
+	p.teleportMu.Lock()
+	defer p.teleportMu.Unlock()
+	p.lastTeleportID++
+	p.pendingTeleport = &teleport{id: p.lastTeleportID, pos: pos}
+	return p.lastTeleportID
+}
+
+// teleporting returns true while the client hasn't confirmed the last teleport
+func (p *Player) teleporting() bool {
+	p.teleportMu.Lock()
+	defer p.teleportMu.Unlock()
+	return p.pendingTeleport != nil
+}
+
+func (p *Player) confirmTeleport(id int32) {
+	p.teleportMu.Lock()
+	t := p.pendingTeleport
+	if t == nil || t.id != id {
+		p.teleportMu.Unlock()
+		return
+	}
+	p.pendingTeleport = nil
+	p.teleportMu.Unlock()
+
+	p.moveMu.Lock()
+	p.FeetPos = t.pos
+	p.moveMu.Unlock()
+	p.updateView()
+}
+
+// Teleport moves the player to pos in the dimension they are in
+func (p *Player) Teleport(pos pstn.Entity) {
+	id := p.expectTeleport(pos)
+	p.queuePacket(playerPosAndLookClientboundID, playerPosAndLook{Pos: pos, ID: id})
+}
+
+// World returns the dimension the player is in
+func (p *Player) World() *worlds.Dimension {
+	p.view.mu.Lock()
+	defer p.view.mu.Unlock()
+	return p.world
+}
+
+// ChangeDimension moves the player to pos in another dimension. The player is only put at pos once every chunk around
+// it was sent, so they don't fall through the world while it loads. It must not be called again for the same player
+// before it returns.
+func (p *Player) ChangeDimension(to *worlds.Dimension, pos pstn.Entity) {
+	id := p.expectTeleport(pos)
+	p.leaveWorld()
+	p.view.mu.Lock()
+	p.world = to
+	p.view.mu.Unlock()
+
+	p.queuePacket(respawnID, respawn{world: to, mode: p.Gamemode()})
+	center := pstn.EntityToChunk(pos)
+	p.queuePacket(updateViewPositionID, proto.NewPacket(func(e *proto.PacketEncoder) {
+		e.WriteVar32(center.X)
+		e.WriteVar32(center.Z)
+	}))
+	to.AddViewer(p)
+	to.AddEntity(p)
+
+	remaining := int32(chunksInView(p.server.ViewDistance))
+	p.moveView(center, func(chunk *chunks.ChunkColumn) {
+		p.queueChunk(chunk)
+		if atomic.AddInt32(&remaining, -1) == 0 {
+			// Queued after the chunks, which are sent separately from other packets
+			p.view.mu.Lock()
+			p.queueViewPacket(playerPosAndLookClientboundID, playerPosAndLook{Pos: pos, ID: id})
+			p.view.mu.Unlock()
+		}
+	})
+}
+
+// leaveWorld removes the player from the dimension they are in, which stops sending them anything from it
+func (p *Player) leaveWorld() {
+	world := p.World()
+	world.RemoveViewer(p)
+	world.RemoveEntity(p)
+	p.releaseView()
+}
+
+var _ worlds.Traveler = (*Player)(nil)
@@ -0,0 +1,181 @@
+package net
+
+import (
+	"context"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	"github.com/masp/mcgo/worlds"
+	"sync"
+)
+
+// chunksInView returns the number of chunks in the square a player can see
+func chunksInView(viewDistance int) int {
+	return (2*viewDistance + 1) * (2*viewDistance + 1)
+}
+
+// chunkView is the square of chunks around a player that the client has loaded, or is about to. Every chunk in it is
+// retained in the player's world.
+type chunkView struct {
+	mu     sync.Mutex
+	center pstn.Chunk
+	chunks map[pstn.Chunk]struct{}
+	// queue are the packets about the view that wait to be sent, in order. They are queued without blocking, since
+	// chunks are queued on the world's workers, and sendViewPackets waits for room in the player's buffer instead.
+	queue  []viewPacket
+	queued chan struct{} // has a value once there is something in queue
+}
+
+type viewPacket struct {
+	world  *worlds.Dimension // the packet is dropped if the player changed dimensions since
+	id     proto.PacketID
+	packet proto.EncodableAsPacket
+}
+
+type unloadChunk struct {
+	Pos pstn.Chunk
+}
+
+func (u unloadChunk) EncodeTo(e *proto.PacketEncoder) {
+	e.WriteI32(u.Pos.X)
+	e.WriteI32(u.Pos.Z)
+}
+
+// ViewsChunk returns true if the chunk is within the player's viewing distance
+func (p *Player) ViewsChunk(pos pstn.Chunk) bool {
+	p.view.mu.Lock()
+	defer p.view.mu.Unlock()
+	_, ok := p.view.chunks[pos]
+	return ok
+}
+
+// moveView changes the player's view to the square around center. Chunks that came into view are requested from the
+// world and passed to send once they are ready, and chunks that went out of view are unloaded on the client.
+func (p *Player) moveView(center pstn.Chunk, send func(chunk *chunks.ChunkColumn)) {
+	p.view.mu.Lock()
+	world := p.world
+	old := p.view.chunks
+	p.view.center = center
+	distance := int32(p.server.ViewDistance)
+	p.view.chunks = make(map[pstn.Chunk]struct{}, chunksInView(p.server.ViewDistance))
+	var added []pstn.Chunk
+	for x := center.X - distance; x <= center.X+distance; x++ {
+		for z := center.Z - distance; z <= center.Z+distance; z++ {
+			pos := pstn.Chunk{X: x, Z: z}
+			p.view.chunks[pos] = struct{}{}
+			if _, ok := old[pos]; ok {
+				delete(old, pos)
+			} else {
+				added = append(added, pos)
+			}
+		}
+	}
+	for pos := range old {
+		world.Release(pos)
+		p.queueViewPacket(unloadChunkID, unloadChunk{pos})
+	}
+	p.view.mu.Unlock()
+
+	for _, pos := range added {
+		world.Retain(pos)
+		world.RequestChunk(pos, func(chunk *chunks.ChunkColumn) {
+			// The player may have changed dimensions while the chunk was loading
+			if p.World() == world {
+				send(chunk)
+			}
+		})
+	}
+}
+
+// queueChunk sends a chunk that was loaded for the player, unless it went out of view while it was loading
+func (p *Player) queueChunk(chunk *chunks.ChunkColumn) {
+	p.view.mu.Lock()
+	defer p.view.mu.Unlock()
+	if _, ok := p.view.chunks[chunk.Pos]; !ok {
+		return
+	}
+	p.queueViewPacket(chunkDataID, chunk)
+	// TODO: Send proper chunk lighting
+	p.queueViewPacket(updateLightID, chunks.ChunkLightingPacket{Chunk: chunk})
+}
+
+// queueViewPacket adds a packet to the view's queue without blocking. view.mu must be held.
+func (p *Player) queueViewPacket(id proto.PacketID, packet proto.EncodableAsPacket) {
+	p.view.queue = append(p.view.queue, viewPacket{world: p.world, id: id, packet: packet})
+	select {
+	case p.view.queued <- struct{}{}:
+	default:
+	}
+}
+
+// sendViewPackets moves the packets of the view's queue to the player's buffer until ctx is done
+func (p *Player) sendViewPackets(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.view.queued:
+		}
+		p.view.mu.Lock()
+		queue := p.view.queue
+		p.view.queue = nil
+		p.view.mu.Unlock()
+		for _, v := range queue {
+			if p.World() == v.world {
+				p.queuePacket(v.id, v.packet)
+			}
+		}
+	}
+}
+
+// sendInitialChunks sends every chunk in view before the player is spawned, so they don't fall out of the world. It
+// gives up once the player or their world stops.
+func sendInitialChunks(p *Player) {
+	n := chunksInView(p.server.ViewDistance)
+	ready := make(chan *chunks.ChunkColumn, n)
+	world := p.World()
+	p.moveView(p.ChunkPos(), func(chunk *chunks.ChunkColumn) {
+		ready <- chunk
+	})
+	for i := 0; i < n; i++ {
+		var chunk *chunks.ChunkColumn
+		select {
+		case chunk = <-ready:
+		case <-world.Done():
+			return
+		case <-p.stopped:
+			return
+		}
+		p.sendPacketImmediately(chunkDataID, chunk)
+		// TODO: Send proper chunk lighting
+		p.sendPacketImmediately(updateLightID, chunks.ChunkLightingPacket{Chunk: chunk})
+	}
+}
+
+// updateView moves the view along with the player when they enter another chunk
+func (p *Player) updateView() {
+	center := p.ChunkPos()
+	p.view.mu.Lock()
+	moved := center != p.view.center
+	p.view.mu.Unlock()
+	if !moved {
+		return
+	}
+
+	p.queuePacket(updateViewPositionID, proto.NewPacket(func(e *proto.PacketEncoder) {
+		e.WriteVar32(center.X)
+		e.WriteVar32(center.Z)
+	}))
+	p.moveView(center, p.queueChunk)
+}
+
+// releaseView lets the world evict the chunks the player had in view once they leave
+func (p *Player) releaseView() {
+	p.view.mu.Lock()
+	defer p.view.mu.Unlock()
+	for pos := range p.view.chunks {
+		p.world.Release(pos)
+	}
+	p.view.chunks = nil
+	p.view.queue = nil
+}
@@ -0,0 +1,91 @@
+package net
+
+import (
+	"bytes"
+	"context"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	"testing"
+	"time"
+)
+
+func TestPlayer_queueChunk(t *testing.T) {
+	p := newTestPlayer(t, Creative, nil)
+	p.server.ViewDistance = 0 // only the chunk at the origin, which is loaded, so it's queued right away
+	p.view.queued = make(chan struct{}, 1)
+	for len(p.packetsToSend) < cap(p.packetsToSend) {
+		p.packetsToSend <- nil
+	}
+
+	// A full buffer must not hold up the world's workers, or the tick checking who views a chunk
+	moved := make(chan struct{})
+	go func() {
+		p.moveView(pstn.Chunk{}, p.queueChunk)
+		p.moveView(pstn.Chunk{X: 5}, p.queueChunk)
+		p.ViewsChunk(pstn.Chunk{})
+		close(moved)
+	}()
+	select {
+	case <-moved:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("moving the view blocked on the full buffer")
+	}
+
+	sentPackets(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.sendViewPackets(ctx)
+	for _, want := range []int32{chunkDataID, updateLightID, unloadChunkID} {
+		select {
+		case data := <-p.packetsToSend:
+			if id := proto.NewPacketDecoder(bytes.NewReader(data)).ReadVar32(); id != want {
+				t.Fatalf("sent packet 0x%02x, want 0x%02x", id, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("packet 0x%02x wasn't sent", want)
+		}
+	}
+}
+
+func TestPlayer_SendChunkChanges(t *testing.T) {
+	p := newTestPlayer(t, Creative, nil)
+	p.view.queued = make(chan struct{}, 1)
+	for len(p.packetsToSend) < cap(p.packetsToSend) {
+		p.packetsToSend <- nil
+	}
+	chunk := p.World().ChunkAtBlock(pstn.Block{})
+	chunk.TakeChanges()
+	chunk.SetBlockAt(1, 70, 1, blocks.OakSign)
+	chunk.SetBlockEntity(1, 70, 1, chunks.NewSign("hi"))
+	chunk.SetBlockAt(2, 70, 1, blocks.Stone)
+	chunk.SetBlockAt(1, 90, 1, blocks.Stone)
+
+	// The tick sends the changes, so a full buffer must neither hold it up nor lose them
+	sent := make(chan struct{})
+	go func() {
+		p.SendChunkChanges(chunk.TakeChanges())
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("sending the changes blocked on the full buffer")
+	}
+
+	sentPackets(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.sendViewPackets(ctx)
+	for _, want := range []int32{multiBlockChangeID, blockChangeID, blockEntityDataID} {
+		select {
+		case data := <-p.packetsToSend:
+			if id := proto.NewPacketDecoder(bytes.NewReader(data)).ReadVar32(); id != want {
+				t.Fatalf("sent packet 0x%02x, want 0x%02x", id, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("packet 0x%02x wasn't sent", want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package perms decides what players may do by permission nodes like mc.command.ban. Nodes are granted to groups,
+// which can inherit the nodes of other groups, and to single players. A node ending in * grants everything below it,
+// and a node starting with - takes a permission away.
+package perms
+
+import (
+	"strings"
+)
+
+// Wildcard grants every node below the node it ends, or every node on its own
+const Wildcard = "*"
+
+// match returns how specific pattern is for node, or -1 if it doesn't match. An exact match is more specific than any
+// wildcard, and a wildcard is more specific the more segments it has.
+func match(pattern string, node string) int {
+	if pattern == node {
+		return 2*strings.Count(node, ".") + 3
+	}
+	if pattern == Wildcard {
+		return 0
+	}
+	if strings.HasSuffix(pattern, "."+Wildcard) {
+		prefix := pattern[:len(pattern)-1]
+		if strings.HasPrefix(node, prefix) {
+			return 2 * strings.Count(prefix, ".")
+		}
+	}
+	return -1
+}
+
+// Decide returns if nodes grant node, and false for decided if none of them mention it. The most specific node
+// decides, and a negated node wins over a granted one that is as specific.
+func Decide(nodes []string, node string) (allowed bool, decided bool) {
+	best := -1
+	for _, n := range nodes {
+		negated := strings.HasPrefix(n, "-")
+		specificity := match(strings.TrimPrefix(n, "-"), node)
+		if specificity < 0 || specificity < best {
+			continue
+		}
+		if specificity > best || negated {
+			allowed = !negated
+		}
+		best = specificity
+	}
+	return allowed, best >= 0
+}
@@ -0,0 +1,84 @@
+package perms
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		nodes   []string
+		node    string
+		allowed bool
+		decided bool
+	}{
+		{nil, "mc.command.ban", false, false},
+		{[]string{"mc.command.ban"}, "mc.command.ban", true, true},
+		{[]string{"mc.command.ban"}, "mc.command.kick", false, false},
+		{[]string{"mc.command.*"}, "mc.command.ban", true, true},
+		{[]string{"mc.command.*"}, "mc.commands", false, false},
+		{[]string{"*"}, "mc.block.break", true, true},
+		{[]string{"*", "-mc.command.*"}, "mc.command.op", false, true},
+		{[]string{"-mc.command.*", "mc.command.kick"}, "mc.command.kick", true, true},
+		{[]string{"mc.command.kick", "-mc.command.kick"}, "mc.command.kick", false, true},
+		{[]string{"-mc.*", "mc.command.*"}, "mc.command.ban", true, true},
+	}
+	for _, tt := range tests {
+		allowed, decided := Decide(tt.nodes, tt.node)
+		if allowed != tt.allowed || decided != tt.decided {
+			t.Errorf("Decide(%q, %q) = %v, %v, want %v, %v", tt.nodes, tt.node, allowed, decided, tt.allowed, tt.decided)
+		}
+	}
+}
+
+type opLevels map[uuid.UUID]int
+
+func (o opLevels) Level(id uuid.UUID) int { return o[id] }
+
+func TestStore_HasPermission(t *testing.T) {
+	dir, err := ioutil.TempDir("", "perms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, File)
+
+	admin, builder, guest := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	store, err := Open(path, opLevels{admin: 3})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := store.SetGroup("builder", Group{Inherits: []string{"builder"}, Permissions: []string{"mc.command.gamemode"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetPlayer(builder, Player{Name: "Builder", Groups: []string{"builder"}, Permissions: []string{"-mc.block.break"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen to check everything was written back
+	store, err = Open(path, opLevels{admin: 3})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	tests := []struct {
+		player uuid.UUID
+		node   string
+		want   bool
+	}{
+		{guest, "mc.block.place", true}, // from default
+		{guest, "mc.command.ban", false},
+		{admin, "mc.command.ban", true}, // op3
+		{admin, "mc.command.stop", false},
+		{builder, "mc.command.gamemode", true},
+		{builder, "mc.block.break", false}, // the player's own nodes come first
+		{builder, "mc.block.place", true},
+	}
+	for _, tt := range tests {
+		if got := store.HasPermission(tt.player, tt.node); got != tt.want {
+			t.Errorf("HasPermission(%s, %q) = %v, want %v", store.Player(tt.player).Name, tt.node, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package perms
+
+import (
+	"encoding/json"
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// File is where the permissions are stored, relative to the directory of the server
+const File = "permissions.json"
+
+// DefaultGroup is the group every player is in
+const DefaultGroup = "default"
+
+// OpGroup returns the group operators of a level are in, op1 to op4. The groups inherit each other, so an operator
+// has the permissions of every level up to theirs.
+func OpGroup(level int) string {
+	return "op" + strconv.Itoa(level)
+}
+
+// Group is a set of permission nodes that players can be put in
+type Group struct {
+	// Inherits are groups whose nodes apply to the players in this group too, unless this group decides otherwise
+	Inherits    []string `json:"inherits,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+// Player are the groups and permissions of a single player. They come before the groups of the player, the default
+// group and the group of their operator level.
+type Player struct {
+	Name        string   `json:"name,omitempty"` // only so the file is easier to edit
+	Groups      []string `json:"groups,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// DefaultGroups are the groups of a new permissions file. Operators can run the commands vanilla lets them run at
+// their level, and level 4 can do everything.
+func DefaultGroups() map[string]Group {
+	return map[string]Group{
+		DefaultGroup: {Permissions: []string{"mc.block.*"}},
+		OpGroup(1):   {Inherits: []string{DefaultGroup}, Permissions: []string{}},
+		OpGroup(2):   {Inherits: []string{OpGroup(1)}, Permissions: []string{}},
+		OpGroup(3): {Inherits: []string{OpGroup(2)}, Permissions: []string{
+			"mc.command.whitelist", "mc.command.op", "mc.command.deop", "mc.command.ban", "mc.command.pardon",
+			"mc.command.ban-ip", "mc.command.pardon-ip", "mc.command.banlist", "mc.command.kick",
+		}},
+		OpGroup(4): {Inherits: []string{OpGroup(3)}, Permissions: []string{Wildcard}},
+	}
+}
+
+// OpLevels knows the operator level of players, like access.Ops
+type OpLevels interface {
+	Level(id uuid.UUID) int
+}
+
+type file struct {
+	Groups  map[string]Group  `json:"groups"`
+	Players map[string]Player `json:"players"` // by UUID
+}
+
+// Store keeps the groups and players with their permissions, and writes them back to its file on every change
+type Store struct {
+	path string
+	ops  OpLevels
+
+	mu      sync.RWMutex
+	groups  map[string]Group
+	players map[uuid.UUID]Player
+}
+
+// Open reads the permissions at path. If the file doesn't exist, it's created with DefaultGroups. Operators are put
+// in the group of their level, which is left out if ops is nil.
+func Open(path string, ops OpLevels) (*Store, error) {
+	s := &Store{path: path, ops: ops}
+	return s, s.Reload()
+}
+
+// Reload reads the permissions from their file again, for changes made while the server is running
+func (s *Store) Reload() error {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.groups = DefaultGroups()
+		s.players = make(map[uuid.UUID]Player)
+		return s.save()
+	} else if err != nil {
+		return err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("invalid %s: %w", s.path, err)
+	}
+	players := make(map[uuid.UUID]Player, len(f.Players))
+	for id, p := range f.Players {
+		parsed, err := uuid.FromString(id)
+		if err != nil {
+			return fmt.Errorf("invalid %s: player %q isn't a UUID", s.path, id)
+		}
+		players[parsed] = p
+	}
+	if f.Groups == nil {
+		f.Groups = make(map[string]Group)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups = f.Groups
+	s.players = players
+	return nil
+}
+
+// save writes the permissions to the file, through a temporary file so a crash can't leave half of it behind
+func (s *Store) save() error {
+	f := file{Groups: s.groups, Players: make(map[string]Player, len(s.players))}
+	for id, p := range s.players {
+		f.Players[id.String()] = p
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(s.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(s.path+".tmp", s.path)
+}
+
+// GroupsOf returns the groups of a player in the order they are checked: their own groups, the group of their
+// operator level and the default group
+func (s *Store) GroupsOf(id uuid.UUID) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.groupsOf(id)
+}
+
+func (s *Store) groupsOf(id uuid.UUID) []string {
+	groups := append([]string(nil), s.players[id].Groups...)
+	if s.ops != nil {
+		if level := s.ops.Level(id); level > 0 {
+			groups = append(groups, OpGroup(level))
+		}
+	}
+	return append(groups, DefaultGroup)
+}
+
+// HasPermission returns true if the player is granted the node. The nodes of the player come first, then their
+// groups in order. A group decides before the groups it inherits.
+func (s *Store) HasPermission(id uuid.UUID, node string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if allowed, decided := Decide(s.players[id].Permissions, node); decided {
+		return allowed
+	}
+	visited := make(map[string]bool)
+	for _, group := range s.groupsOf(id) {
+		if allowed, decided := s.decideGroup(group, node, visited); decided {
+			return allowed
+		}
+	}
+	return false
+}
+
+func (s *Store) decideGroup(name string, node string, visited map[string]bool) (bool, bool) {
+	if visited[name] {
+		return false, false // inherited twice, or a cycle
+	}
+	visited[name] = true
+	group := s.groups[name]
+	if allowed, decided := Decide(group.Permissions, node); decided {
+		return allowed, true
+	}
+	for _, parent := range group.Inherits {
+		if allowed, decided := s.decideGroup(parent, node, visited); decided {
+			return allowed, true
+		}
+	}
+	return false, false
+}
+
+// Groups returns the names of every group, sorted
+func (s *Store) Groups() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.groups))
+	for name := range s.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Group returns the group with the given name
+func (s *Store) Group(name string) (Group, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g, ok := s.groups[name]
+	return g, ok
+}
+
+// SetGroup creates or replaces a group
+func (s *Store) SetGroup(name string, g Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[name] = g
+	return s.save()
+}
+
+// RemoveGroup deletes a group. Players and groups that refer to it are left alone, it's as if it were empty for them.
+func (s *Store) RemoveGroup(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[name]; !ok {
+		return false, nil
+	}
+	delete(s.groups, name)
+	return true, s.save()
+}
+
+// Player returns the groups and permissions of a player
+func (s *Store) Player(id uuid.UUID) Player {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.players[id]
+}
+
+// SetPlayer replaces the groups and permissions of a player. Players without either are removed from the file.
+func (s *Store) SetPlayer(id uuid.UUID, p Player) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(p.Groups) == 0 && len(p.Permissions) == 0 {
+		delete(s.players, id)
+	} else {
+		s.players[id] = p
+	}
+	return s.save()
+}
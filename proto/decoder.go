@@ -3,11 +3,14 @@ package proto
 import (
 	"bufio"
 	"bytes"
+	"compress/zlib"
 	"encoding/binary"
 	"fmt"
+	"github.com/Tnze/go-mc/nbt"
 	"github.com/masp/mcgo/pstn"
 	uuid "github.com/satori/go.uuid"
 	"io"
+	"io/ioutil"
 )
 
 type PacketID int32
@@ -33,16 +36,52 @@ type RecvPacket struct {
 }
 
 func (p *PacketDecoder) ReadPacket() RecvPacket {
+	return newRecvPacket(p.readFrame())
+}
+
+// ReadCompressedPacket reads a packet that is framed for a connection with compression enabled, see
+// PacketEncoder.WriteCompressedPacket
+func (p *PacketDecoder) ReadCompressedPacket() RecvPacket {
+	frame := PacketDecoder{bytes.NewReader(p.readFrame())}
+	dataLength := frame.ReadVar32()
+	if dataLength == 0 { // too small to be compressed
+		rest, _ := ioutil.ReadAll(frame)
+		return newRecvPacket(rest)
+	}
+	if dataLength < 0 || dataLength > maxPacketLength {
+		panic(fmt.Errorf("invalid uncompressed packet size %d", dataLength))
+	}
+
+	zr, err := zlib.NewReader(frame)
+	if err != nil {
+		panic(fmt.Errorf("invalid compressed packet: %w", err))
+	}
+	payload := make([]byte, dataLength)
+	if _, err := io.ReadFull(zr, payload); err != nil {
+		panic(fmt.Errorf("invalid compressed packet: %w", err))
+	}
+	return newRecvPacket(payload)
+}
+
+// maxPacketLength is the most a client can send in one packet, anything longer is rejected before it's read
+const maxPacketLength = 1 << 21
+
+// readFrame reads the length prefixed bytes of the next packet
+func (p *PacketDecoder) readFrame() []byte {
 	length := p.ReadVar32()
-	if length <= 0 {
+	if length <= 0 || length > maxPacketLength {
 		panic(fmt.Errorf("invalid packet sizing %d (must be > 0)", length))
 	}
 
 	payload := make([]byte, length)
-	_, err := p.Read(payload)
+	_, err := io.ReadFull(p, payload)
 	if err != nil {
 		panic(fmt.Errorf("failed reading %d bytes for packet payload: %w", length, err))
 	}
+	return payload
+}
+
+func newRecvPacket(payload []byte) RecvPacket {
 	payloadDecoder := PacketDecoder{bytes.NewReader(payload)}
 
 	id := PacketID(payloadDecoder.ReadVar32())
@@ -126,6 +165,24 @@ func (p *PacketDecoder) ReadUUID() uuid.UUID {
 	return uuid.FromBytesOrNil(bs)
 }
 
+// ReadNBT reads an NBT compound into v, like the data of an item. It returns false if there is none, which is sent as
+// a single end tag.
+func (p *PacketDecoder) ReadNBT(v interface{}) bool {
+	rd := p.Reader.(nbt.DecoderReader) // always assume we are using a buffered reader
+	tag, err := rd.ReadByte()
+	if err != nil {
+		panic(err)
+	}
+	if tag == 0 {
+		return false
+	}
+	must(rd.UnreadByte())
+	if err := nbt.NewDecoder(rd).Decode(v); err != nil {
+		panic(fmt.Errorf("invalid NBT in packet: %w", err))
+	}
+	return true
+}
+
 func (p *PacketDecoder) mustRead(data interface{}) {
 	err := binary.Read(p, binary.BigEndian, data)
 	if err != nil {
@@ -3,6 +3,7 @@ package proto
 import (
 	"bufio"
 	"bytes"
+	"compress/zlib"
 	"encoding/binary"
 	"fmt"
 	"github.com/Tnze/go-mc/nbt"
@@ -156,3 +157,20 @@ func (e *PacketEncoder) WritePacket(packet []byte) {
 	e.WriteVar32(int32(len(packet)))
 	mustWrite(e.Write(packet))
 }
+
+// WriteCompressedPacket writes a packet framed for a connection with compression enabled. Packets of at least threshold
+// bytes are compressed with zlib, smaller ones are sent as they are.
+func (e *PacketEncoder) WriteCompressedPacket(packet []byte, threshold int) {
+	var b bytes.Buffer
+	frame := NewEncoder(&b)
+	if len(packet) < threshold {
+		frame.WriteVar32(0)
+		mustWrite(frame.Write(packet))
+	} else {
+		frame.WriteVar32(int32(len(packet)))
+		zw := zlib.NewWriter(&b)
+		mustWrite(zw.Write(packet))
+		must(zw.Close())
+	}
+	e.WritePacket(b.Bytes())
+}
@@ -3,6 +3,7 @@ package proto
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -35,3 +36,27 @@ func TestPacketEncoder_WriteVar64(t *testing.T) {
 		})
 	}
 }
+
+func TestPacketEncoder_WriteCompressedPacket(t *testing.T) {
+	small := EncodePacket(0x01, NewPacket(func(e *PacketEncoder) { e.WriteU16(0x50) }))
+	large := EncodePacket(0x02, NewPacket(func(e *PacketEncoder) { e.WriteString(strings.Repeat("chunk", 100)) }))
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.WriteCompressedPacket(small, 256)
+	e.WriteCompressedPacket(large, 256)
+	if want := []byte{0x04, 0x00, 0x01, 0x00, 0x50}; !bytes.Equal(b.Bytes()[:5], want) {
+		t.Errorf("small packet was written as %v, want %v uncompressed", b.Bytes()[:5], want)
+	}
+	if b.Len() >= len(small)+len(large) {
+		t.Errorf("large packet wasn't compressed, wrote %d bytes", b.Len())
+	}
+
+	d := NewPacketDecoder(&b)
+	if p := d.ReadCompressedPacket(); p.ID != 0x01 || p.ReadU16() != 0x50 {
+		t.Errorf("small packet wasn't read back")
+	}
+	if p := d.ReadCompressedPacket(); p.ID != 0x02 || p.ReadString() != strings.Repeat("chunk", 100) {
+		t.Errorf("large packet wasn't read back")
+	}
+}
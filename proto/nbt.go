@@ -0,0 +1,212 @@
+package proto
+
+import (
+	"fmt"
+	"github.com/Tnze/go-mc/nbt"
+	"io"
+	"math"
+	"sort"
+)
+
+// WriteCompound writes an NBT compound that doesn't have a fixed shape, such as block entity data or anything read
+// from a save file. go-mc's nbt package can decode these into a map[string]interface{} but can't encode maps, so
+// this fills the gap. Keys are written in sorted order so the output is deterministic.
+//
+// Supported values are the ones go-mc decodes into: uint8/int8/bool (byte), int16, int32/int, int64, float32,
+// float64, string, []byte, []int32, []int64, []interface{} (list), map[string]interface{} and map[string]string
+// (compounds).
+func WriteCompound(w io.Writer, name string, c map[string]interface{}) error {
+	nw := nbtWriter{w}
+	return nw.writeNamed(nbt.TagCompound, name, c)
+}
+
+func (e *PacketEncoder) WriteCompound(c map[string]interface{}) {
+	must(WriteCompound(e.Writer, "", c))
+}
+
+type nbtWriter struct {
+	io.Writer
+}
+
+func nbtTagType(v interface{}) (byte, error) {
+	switch v.(type) {
+	case uint8, int8, bool:
+		return nbt.TagByte, nil
+	case int16:
+		return nbt.TagShort, nil
+	case int32, int:
+		return nbt.TagInt, nil
+	case int64:
+		return nbt.TagLong, nil
+	case float32:
+		return nbt.TagFloat, nil
+	case float64:
+		return nbt.TagDouble, nil
+	case string:
+		return nbt.TagString, nil
+	case []byte:
+		return nbt.TagByteArray, nil
+	case []int32:
+		return nbt.TagIntArray, nil
+	case []int64:
+		return nbt.TagLongArray, nil
+	case []interface{}, []map[string]interface{}:
+		return nbt.TagList, nil
+	case map[string]interface{}, map[string]string:
+		return nbt.TagCompound, nil
+	default:
+		return nbt.TagNone, fmt.Errorf("nbt: unsupported value of type %T", v)
+	}
+}
+
+func (w nbtWriter) writeNamed(tagType byte, name string, v interface{}) error {
+	if err := w.writeRaw(tagType); err != nil {
+		return err
+	}
+	if err := w.writeString(name); err != nil {
+		return err
+	}
+	return w.writeValue(v)
+}
+
+func (w nbtWriter) writeRaw(data ...byte) error {
+	_, err := w.Write(data)
+	return err
+}
+
+func (w nbtWriter) writeI16(v int16) error {
+	return w.writeRaw(byte(v>>8), byte(v))
+}
+
+func (w nbtWriter) writeI32(v int32) error {
+	return w.writeRaw(byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (w nbtWriter) writeI64(v int64) error {
+	return w.writeRaw(byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+		byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (w nbtWriter) writeString(s string) error {
+	if err := w.writeI16(int16(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func (w nbtWriter) writeValue(v interface{}) error {
+	switch v := v.(type) {
+	case uint8:
+		return w.writeRaw(v)
+	case int8:
+		return w.writeRaw(byte(v))
+	case bool:
+		if v {
+			return w.writeRaw(1)
+		}
+		return w.writeRaw(0)
+	case int16:
+		return w.writeI16(v)
+	case int32:
+		return w.writeI32(v)
+	case int:
+		return w.writeI32(int32(v))
+	case int64:
+		return w.writeI64(v)
+	case float32:
+		return w.writeI32(int32(math.Float32bits(v)))
+	case float64:
+		return w.writeI64(int64(math.Float64bits(v)))
+	case string:
+		return w.writeString(v)
+	case []byte:
+		if err := w.writeI32(int32(len(v))); err != nil {
+			return err
+		}
+		return w.writeRaw(v...)
+	case []int32:
+		if err := w.writeI32(int32(len(v))); err != nil {
+			return err
+		}
+		for _, i := range v {
+			if err := w.writeI32(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []int64:
+		if err := w.writeI32(int32(len(v))); err != nil {
+			return err
+		}
+		for _, i := range v {
+			if err := w.writeI64(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, c := range v {
+			list[i] = c
+		}
+		return w.writeList(list)
+	case []interface{}:
+		return w.writeList(v)
+	case map[string]string:
+		c := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			c[k] = s
+		}
+		return w.writeCompound(c)
+	case map[string]interface{}:
+		return w.writeCompound(v)
+	}
+	return fmt.Errorf("nbt: unsupported value of type %T", v)
+}
+
+func (w nbtWriter) writeList(list []interface{}) error {
+	elemType := nbt.TagEnd // empty lists are written as a list of TagEnd like vanilla does
+	if len(list) > 0 {
+		var err error
+		if elemType, err = nbtTagType(list[0]); err != nil {
+			return err
+		}
+	}
+	if err := w.writeRaw(elemType); err != nil {
+		return err
+	}
+	if err := w.writeI32(int32(len(list))); err != nil {
+		return err
+	}
+	for _, elem := range list {
+		if t, err := nbtTagType(elem); err != nil {
+			return err
+		} else if t != elemType {
+			return fmt.Errorf("nbt: list contains mixed types %T and %T", list[0], elem)
+		}
+		if err := w.writeValue(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w nbtWriter) writeCompound(c map[string]interface{}) error {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		tagType, err := nbtTagType(c[k])
+		if err != nil {
+			return fmt.Errorf("nbt: tag %q: %w", k, err)
+		}
+		if err := w.writeNamed(tagType, k, c[k]); err != nil {
+			return err
+		}
+	}
+	return w.writeRaw(nbt.TagEnd)
+}
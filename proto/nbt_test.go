@@ -0,0 +1,64 @@
+package proto
+
+import (
+	"bytes"
+	"github.com/Tnze/go-mc/nbt"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCompound(t *testing.T) {
+	// Values use the types go-mc decodes into, so the compound should survive a round trip untouched
+	want := map[string]interface{}{
+		"id":     "minecraft:chest",
+		"x":      int32(-4),
+		"Lock":   "",
+		"Byte":   byte(3),
+		"Short":  int16(-2),
+		"Long":   int64(1) << 40,
+		"Float":  float32(0.5),
+		"Double": 2.25,
+		"Bytes":  []byte{1, 2, 3},
+		"Ints":   []int32{4, 5},
+		"Longs":  []int64{6},
+		"Items": []interface{}{
+			map[string]interface{}{"Slot": byte(0), "id": "minecraft:stone", "Count": byte(64)},
+		},
+		"Empty": []interface{}{},
+	}
+
+	var b bytes.Buffer
+	if err := WriteCompound(&b, "", want); err != nil {
+		t.Fatalf("WriteCompound() error = %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := nbt.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("nbt.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %v, want %v", got, want)
+	}
+}
+
+func TestPacketDecoder_ReadNBT(t *testing.T) {
+	want := map[string]interface{}{"Damage": int32(3)}
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.WriteCompound(want)
+	e.WriteU8(0) // no NBT
+	e.WriteVar32(42)
+	e.Flush()
+
+	d := PacketDecoder{bytes.NewReader(b.Bytes())}
+	var got map[string]interface{}
+	if !d.ReadNBT(&got) || !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadNBT() read %v, want %v", got, want)
+	}
+	if d.ReadNBT(&got) {
+		t.Errorf("ReadNBT() of an end tag returned true")
+	}
+	if n := d.ReadVar32(); n != 42 {
+		t.Errorf("ReadNBT() didn't stop after the compound, read %d next", n)
+	}
+}
@@ -1,5 +1,7 @@
 package pstn
 
+import "math"
+
 type Chunk struct {
 	X, Z int32
 }
@@ -17,11 +19,17 @@ const (
 )
 
 func BlockToChunk(pos Block) Chunk {
-	return Chunk{X: pos.X / chunkSize, Z: pos.Z / chunkSize}
+	// Shift instead of divide so negative coordinates round down to the chunk they are in
+	return Chunk{X: pos.X >> 4, Z: pos.Z >> 4}
+}
+
+// BlockInChunk returns the position of the block relative to the chunk column that contains it
+func BlockInChunk(pos Block) (x int, y int, z int) {
+	return int(pos.X & (chunkSize - 1)), int(pos.Y), int(pos.Z & (chunkSize - 1))
 }
 
 func EntityToChunk(pos Entity) Chunk {
-	return Chunk{X: int32(pos.X) / 16, Z: int32(pos.Z) / 16}
+	return Chunk{X: int32(math.Floor(pos.X / chunkSize)), Z: int32(math.Floor(pos.Z / chunkSize))}
 }
 
 func BlockToEntity(pos Block) Entity {
@@ -0,0 +1,273 @@
+// Package rcon is a remote console for administering the server, compatible with the RCON tools made for vanilla
+// servers. Clients log in with a password and then run commands like the console, getting back what the commands
+// replied.
+package rcon
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/masp/mcgo/chat"
+	"github.com/masp/mcgo/command"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultPort is the port vanilla servers listen for RCON on
+const DefaultPort = 25575
+
+// Name is how RCON clients appear as the sender of commands, like vanilla
+const Name = "Rcon"
+
+// Types of packets
+const (
+	typeResponse = 0 // sent back with the output of a command
+	typeCommand  = 2 // runs a command
+	typeLogin    = 3 // logs in with the password
+	// typeLoginResponse answers a login. It shares its value with typeCommand, which only clients send.
+	typeLoginResponse = 2
+)
+
+const (
+	// maxRequestSize is the largest packet clients may send, without the length
+	maxRequestSize = 1460
+	// maxResponseBody is how much output fits in one response, longer output is split over several packets with the
+	// same id. Clients tell where it ends by sending another packet right after the command, which is answered after
+	// the last part.
+	maxResponseBody = 4096
+	// headerSize is the size of the id and type, and the null bytes after the body
+	headerSize = 4 + 4 + 2
+	// loginFailedID is the id of the login response when the password is wrong
+	loginFailedID = -1
+)
+
+// ErrServerClosed is returned by Serve once the server is closed
+var ErrServerClosed = errors.New("rcon: server closed")
+
+// packet is a request or response. Unlike the rest of the protocol, its numbers are little endian.
+type packet struct {
+	id   int32
+	typ  int32
+	body string
+}
+
+// readPacket reads a packet of at most max bytes, without the length
+func readPacket(r io.Reader, max int32) (packet, error) {
+	var length int32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return packet{}, err
+	}
+	if length < headerSize || length > max {
+		return packet{}, fmt.Errorf("invalid packet length %d", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return packet{}, err
+	}
+	body := buf[8 : length-2]
+	if i := strings.IndexByte(string(body), 0); i >= 0 {
+		body = body[:i]
+	}
+	return packet{
+		id:   int32(binary.LittleEndian.Uint32(buf)),
+		typ:  int32(binary.LittleEndian.Uint32(buf[4:])),
+		body: string(body),
+	}, nil
+}
+
+func writePacket(w io.Writer, p packet) error {
+	buf := make([]byte, 4+headerSize+len(p.body))
+	binary.LittleEndian.PutUint32(buf, uint32(headerSize+len(p.body)))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(p.id))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(p.typ))
+	copy(buf[12:], p.body)
+	_, err := w.Write(buf)
+	return err
+}
+
+// writeResponse sends output to the request with id, split over as many packets as it needs
+func writeResponse(w io.Writer, id int32, output string) error {
+	for {
+		part := output
+		if len(part) > maxResponseBody {
+			end := maxResponseBody
+			for end > 0 && !utf8.RuneStart(part[end]) { // don't split a character over two packets
+				end--
+			}
+			part = part[:end]
+		}
+		output = output[len(part):]
+		if err := writePacket(w, packet{id: id, typ: typeResponse, body: part}); err != nil {
+			return err
+		}
+		if output == "" {
+			return nil
+		}
+	}
+}
+
+// Server accepts RCON clients and runs their commands with Commands
+type Server struct {
+	Password string
+	Commands *command.Dispatcher
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+}
+
+// NewServer returns a server that only lets clients with password run commands. The password may not be empty.
+func NewServer(password string, commands *command.Dispatcher) *Server {
+	return &Server{
+		Password:  password,
+		Commands:  commands,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve accepts clients on ln and handles each on its own goroutine, until the server is closed
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[ln] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, ln)
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				log.Errorf("failed to accept RCON connection: %v", err)
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Close stops accepting clients and disconnects the ones that are connected. Commands that are running finish, but
+// their output isn't sent anymore.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for ln := range s.listeners {
+		ln.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+// handle answers the requests of a client until it disconnects
+func (s *Server) handle(conn net.Conn) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	log.Debugf("RCON client %s connected", conn.RemoteAddr())
+	r := bufio.NewReader(conn)
+	loggedIn := false
+	for {
+		req, err := readPacket(r, maxRequestSize)
+		if err != nil {
+			if err != io.EOF && !s.isClosed() {
+				log.Debugf("RCON client %s disconnected: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		switch {
+		case req.typ == typeLogin:
+			// Compared in constant time, so the time it takes doesn't tell how much of the password was right
+			loggedIn = subtle.ConstantTimeCompare([]byte(req.body), []byte(s.Password)) == 1
+			id := req.id
+			if !loggedIn {
+				id = loginFailedID
+				log.Warnf("RCON client %s used the wrong password", conn.RemoteAddr())
+			}
+			err = writePacket(conn, packet{id: id, typ: typeLoginResponse})
+		case !loggedIn:
+			err = writePacket(conn, packet{id: loginFailedID, typ: typeLoginResponse})
+		case req.typ == typeCommand:
+			err = writeResponse(conn, req.id, s.run(req.body))
+		default:
+			err = writeResponse(conn, req.id, fmt.Sprintf("Unknown request %x", req.typ))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// run executes line and returns what the command replied
+func (s *Server) run(line string) string {
+	sender := &sender{}
+	line = strings.TrimPrefix(strings.TrimSpace(line), "/")
+	if line != "" {
+		_ = s.Commands.Execute(sender, line)
+	}
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	return strings.Join(sender.output, "\n")
+}
+
+// sender collects what commands reply to an RCON client, which may run every command like the console
+type sender struct {
+	mu     sync.Mutex // commands may reply from other goroutines
+	output []string
+}
+
+func (s *sender) Name() string {
+	return Name
+}
+
+func (s *sender) SendMessage(msg chat.Component) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.output = append(s.output, msg.PlainText())
+}
+
+func (s *sender) HasPermission(node string) bool {
+	return true
+}
@@ -0,0 +1,93 @@
+package rcon
+
+import (
+	"github.com/masp/mcgo/command"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	d := command.NewDispatcher()
+	long := strings.Repeat("é", maxResponseBody) // two bytes each, so it needs three packets
+	_ = d.Register(&command.Command{Name: "say", Run: func(ctx *command.Context) error {
+		ctx.Reply("[%s] %s", ctx.Sender.Name(), ctx.Rest(0))
+		ctx.Reply("%s", long)
+		return nil
+	}})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer("secret", d)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	request := func(p packet) {
+		t.Helper()
+		if err := writePacket(conn, p); err != nil {
+			t.Fatalf("writePacket() error = %v", err)
+		}
+	}
+	response := func() packet {
+		t.Helper()
+		p, err := readPacket(conn, headerSize+maxResponseBody)
+		if err != nil {
+			t.Fatalf("readPacket() error = %v", err)
+		}
+		return p
+	}
+
+	request(packet{id: 1, typ: typeCommand, body: "say too early"})
+	if p := response(); p.id != loginFailedID || p.typ != typeLoginResponse {
+		t.Errorf("command before logging in got %+v, want a failed login", p)
+	}
+	request(packet{id: 2, typ: typeLogin, body: "wrong"})
+	if p := response(); p.id != loginFailedID {
+		t.Errorf("login with the wrong password got id %d, want %d", p.id, loginFailedID)
+	}
+	request(packet{id: 3, typ: typeLogin, body: "secret"})
+	if p := response(); p.id != 3 || p.typ != typeLoginResponse {
+		t.Errorf("login got %+v, want id 3", p)
+	}
+
+	// Like RCON tools, an unknown packet follows the command to tell when its output ends
+	request(packet{id: 4, typ: typeCommand, body: "/say hello"})
+	request(packet{id: 5, typ: typeResponse})
+	var output strings.Builder
+	parts := 0
+	for {
+		p := response()
+		if p.id == 5 {
+			if p.body != "Unknown request 0" {
+				t.Errorf("unknown request got %q", p.body)
+			}
+			break
+		}
+		if p.id != 4 || p.typ != typeResponse {
+			t.Fatalf("command got %+v, want a response to 4", p)
+		}
+		output.WriteString(p.body)
+		parts++
+	}
+	if want := "[Rcon] hello\n" + long; output.String() != want {
+		t.Errorf("command output = %.40q..., want %.40q...", output.String(), want)
+	}
+	if parts != 3 {
+		t.Errorf("command output came in %d packets, want 3", parts)
+	}
+
+	s.Close()
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Serve() = %v, want ErrServerClosed", err)
+	}
+	if _, err := readPacket(conn, headerSize+maxResponseBody); err == nil {
+		t.Errorf("client is still connected after Close()")
+	}
+}
@@ -0,0 +1,117 @@
+package terrain
+
+import "math"
+
+// random is a splitmix64 generator. It's used instead of math/rand so the terrain for a seed can never change
+// between Go versions.
+type random uint64
+
+func (r *random) next() uint64 {
+	*r += 0x9E3779B97F4A7C15
+	z := uint64(*r)
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// perlin is Ken Perlin's improved noise with a permutation table shuffled by the seed
+type perlin struct {
+	perm [512]uint8
+	// offsets keep noise from different seeds from all being 0 at the origin
+	ox, oy, oz float64
+}
+
+func newPerlin(seed int64) *perlin {
+	r := random(seed)
+	p := &perlin{}
+	for i := 0; i < 256; i++ {
+		p.perm[i] = uint8(i)
+	}
+	for i := 255; i > 0; i-- {
+		j := int(r.next() % uint64(i+1))
+		p.perm[i], p.perm[j] = p.perm[j], p.perm[i]
+	}
+	copy(p.perm[256:], p.perm[:256])
+	p.ox = float64(r.next()%1024) + 0.5
+	p.oy = float64(r.next()%1024) + 0.5
+	p.oz = float64(r.next()%1024) + 0.5
+	return p
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+func grad(hash uint8, x, y, z float64) float64 {
+	h := hash & 15
+	u, v := y, z
+	if h < 8 {
+		u = x
+	}
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// noise3 returns the noise at x, y, z, roughly in [-1, 1]
+func (p *perlin) noise3(x, y, z float64) float64 {
+	x, y, z = x+p.ox, y+p.oy, z+p.oz
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	xi, yi, zi := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z = x-fx, y-fy, z-fz
+	u, v, w := fade(x), fade(y), fade(z)
+
+	perm := &p.perm
+	a := int(perm[xi]) + yi
+	aa, ab := int(perm[a])+zi, int(perm[a+1])+zi
+	b := int(perm[xi+1]) + yi
+	ba, bb := int(perm[b])+zi, int(perm[b+1])+zi
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(perm[aa], x, y, z), grad(perm[ba], x-1, y, z)),
+			lerp(u, grad(perm[ab], x, y-1, z), grad(perm[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(perm[aa+1], x, y, z-1), grad(perm[ba+1], x-1, y, z-1)),
+			lerp(u, grad(perm[ab+1], x, y-1, z-1), grad(perm[bb+1], x-1, y-1, z-1))))
+}
+
+func (p *perlin) noise2(x, z float64) float64 {
+	return p.noise3(x, 0, z)
+}
+
+// octaves sums several layers of noise, each at double the frequency and half the amplitude of the one before, so
+// there are large features with smaller details on top. The result is normalized to roughly [-1, 1].
+type octaves []*perlin
+
+func newOctaves(seed int64, n int) octaves {
+	r := random(seed)
+	o := make(octaves, n)
+	for i := range o {
+		o[i] = newPerlin(int64(r.next()))
+	}
+	return o
+}
+
+func (o octaves) noise2(x, z float64) float64 {
+	var sum, amplitude, total float64 = 0, 1, 0
+	for _, p := range o {
+		sum += p.noise2(x, z) * amplitude
+		total += amplitude
+		x, z, amplitude = x*2, z*2, amplitude/2
+	}
+	return sum / total
+}
@@ -0,0 +1,168 @@
+// Package terrain generates natural looking overworld terrain out of noise, with hills, a few biomes, oceans and caves.
+package terrain
+
+import (
+	"github.com/masp/mcgo/biome"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+)
+
+const (
+	SeaLevel = 63
+
+	baseHeight      = 70
+	heightVariation = 28
+	heightScale     = 1.0 / 160 // blocks to noise units, larger features for smaller values
+	climateScale    = 1.0 / 600
+	caveScale       = 1.0 / 24
+	caveThreshold   = 0.4
+	soilDepth       = 3
+)
+
+// Salts make every noise of a seed different from the others
+const (
+	heightSalt int64 = iota + 1
+	temperatureSalt
+	humiditySalt
+	caveSalt
+)
+
+var snowyGrass = blocks.MustGet("minecraft:grass_block", map[string]string{"snowy": "true"})
+
+// Generator generates terrain from noise. It has no state, so it can generate chunks of several seeds at the same time.
+type Generator struct{}
+
+func New() *Generator {
+	return &Generator{}
+}
+
+// noises are all the noise functions for a seed
+type noises struct {
+	height      octaves
+	temperature octaves
+	humidity    octaves
+	caves       *perlin
+}
+
+func newNoises(seed int64) *noises {
+	return &noises{
+		height:      newOctaves(seed^heightSalt, 5),
+		temperature: newOctaves(seed^temperatureSalt, 2),
+		humidity:    newOctaves(seed^humiditySalt, 2),
+		caves:       newPerlin(seed ^ caveSalt),
+	}
+}
+
+// column is the shape of the terrain at one x, z position
+type column struct {
+	height int // y of the top solid block
+	biome  int32
+}
+
+func (n *noises) column(x, z int) column {
+	fx, fz := float64(x), float64(z)
+	height := baseHeight + int(n.height.noise2(fx*heightScale, fz*heightScale)*heightVariation*2)
+	temperature := n.temperature.noise2(fx*climateScale, fz*climateScale)
+	humidity := n.humidity.noise2(fx*climateScale, fz*climateScale)
+	return column{height: height, biome: selectBiome(height, temperature, humidity)}
+}
+
+// selectBiome picks the biome from the height of the terrain and the climate, which are in [-1, 1]
+func selectBiome(height int, temperature, humidity float64) int32 {
+	switch {
+	case height < SeaLevel-1:
+		return biome.OceanID
+	case height <= SeaLevel+1:
+		return biome.BeachID
+	case temperature < -0.25:
+		return biome.SnowyTundraID
+	case temperature > 0.2 && humidity < 0:
+		return biome.DesertID
+	case humidity > 0.1:
+		return biome.ForestID
+	default:
+		return biome.PlainsID
+	}
+}
+
+// surface returns the top block and the blocks right under it for a biome
+func surface(b int32, underwater bool) (top chunks.BlockState, soil chunks.BlockState) {
+	switch {
+	case underwater && b == biome.OceanID:
+		return blocks.Gravel, blocks.Gravel
+	case b == biome.DesertID:
+		return blocks.Sand, blocks.Sandstone
+	case b == biome.BeachID:
+		return blocks.Sand, blocks.Sand
+	case underwater:
+		return blocks.Dirt, blocks.Dirt
+	case b == biome.SnowyTundraID:
+		return snowyGrass, blocks.Dirt
+	default:
+		return blocks.GrassBlock, blocks.Dirt
+	}
+}
+
+func (g *Generator) Generate(pos pstn.Chunk, seed int64) *chunks.ChunkColumn {
+	n := newNoises(seed)
+	chunk := chunks.NewChunk(pos)
+	baseX, baseZ := int(pos.X)*chunks.Width, int(pos.Z)*chunks.Depth
+
+	var columns [chunks.Width][chunks.Depth]column
+	for x := 0; x < chunks.Width; x++ {
+		for z := 0; z < chunks.Depth; z++ {
+			columns[x][z] = n.column(baseX+x, baseZ+z)
+			g.fillColumn(chunk, n, x, z, baseX+x, baseZ+z, columns[x][z])
+		}
+	}
+
+	// Biomes are stored per 4x4x4 cell, so take the biome in the middle of each cell
+	for x := 0; x < chunks.Width; x += chunks.BiomeCellSize {
+		for z := 0; z < chunks.Depth; z += chunks.BiomeCellSize {
+			b := columns[x+chunks.BiomeCellSize/2][z+chunks.BiomeCellSize/2].biome
+			for y := 0; y < chunks.Height; y += chunks.BiomeCellSize {
+				chunk.SetBiomeAt(x, y, z, b)
+			}
+		}
+	}
+	return chunk
+}
+
+func (g *Generator) fillColumn(chunk *chunks.ChunkColumn, n *noises, x, z int, worldX, worldZ int, c column) {
+	underwater := c.height < SeaLevel
+	top, soil := surface(c.biome, underwater)
+
+	for y := 0; y <= c.height; y++ {
+		var state chunks.BlockState
+		switch {
+		case y == 0:
+			state = blocks.Bedrock
+		case y == c.height:
+			state = top
+		case y > c.height-soilDepth:
+			state = soil
+		default:
+			state = blocks.Stone
+		}
+
+		// Carve caves out of the stone, but not close to the surface so they don't flood or leave floating blocks
+		if y > 4 && y < c.height-soilDepth-2 && isCave(n, worldX, y, worldZ) {
+			continue
+		}
+		chunk.SetBlockAt(x, y, z, state)
+	}
+
+	for y := c.height + 1; y <= SeaLevel; y++ {
+		chunk.SetBlockAt(x, y, z, blocks.Water)
+	}
+	if !underwater && c.biome == biome.SnowyTundraID {
+		chunk.SetBlockAt(x, c.height+1, z, blocks.Snow)
+	}
+}
+
+func isCave(n *noises, x, y, z int) bool {
+	// Squash the noise vertically so caves are wider than they are tall
+	v := n.caves.noise3(float64(x)*caveScale, float64(y)*caveScale*2, float64(z)*caveScale)
+	return v > caveThreshold
+}
@@ -0,0 +1,100 @@
+package terrain
+
+import (
+	"flag"
+	"fmt"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// describeColumn lists the blocks of a column from the bottom up, with repeated blocks collapsed like a flat preset
+func describeColumn(chunk *chunks.ChunkColumn, x, z int) string {
+	var layers []string
+	count, prev := 0, chunks.BlockState(0)
+	flush := func() {
+		name, _, _ := blocks.Lookup(prev)
+		if count > 1 {
+			name = fmt.Sprintf("%d*%s", count, name)
+		}
+		layers = append(layers, name)
+	}
+	for y := 0; y < chunks.Height; y++ {
+		state := chunk.BlockAt(x, y, z)
+		if y > 0 && state != prev {
+			flush()
+			count = 0
+		}
+		prev = state
+		count++
+	}
+	flush()
+	return strings.Join(layers, ",")
+}
+
+func TestGenerator_Golden(t *testing.T) {
+	const seed = 42
+	columns := []struct {
+		chunk pstn.Chunk
+		x, z  int
+	}{
+		{pstn.Chunk{X: 0, Z: 0}, 0, 0},
+		{pstn.Chunk{X: -5, Z: 12}, 7, 3},
+		{pstn.Chunk{X: 31, Z: -7}, 15, 15},
+		{pstn.Chunk{X: -60, Z: -40}, 8, 8},
+		{pstn.Chunk{X: 100, Z: 100}, 2, 9},
+		{pstn.Chunk{X: -100, Z: -79}, 8, 8}, // ocean
+		{pstn.Chunk{X: -100, Z: -31}, 8, 8}, // desert
+		{pstn.Chunk{X: -100, Z: 68}, 8, 8},  // snowy tundra
+	}
+
+	gen := New()
+	var got strings.Builder
+	for _, c := range columns {
+		chunk := gen.Generate(c.chunk, seed)
+		fmt.Fprintf(&got, "chunk %d,%d column %d,%d biome %d: %s\n", c.chunk.X, c.chunk.Z, c.x, c.z,
+			chunk.BiomeAt(c.x, 64, c.z), describeColumn(chunk, c.x, c.z))
+	}
+
+	golden := filepath.Join("testdata", "columns.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(got.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("generated columns don't match %s (run with -update if this is intended)\ngot:\n%s\nwant:\n%s",
+			golden, got.String(), want)
+	}
+}
+
+func TestGenerator_Deterministic(t *testing.T) {
+	gen := New()
+	pos := pstn.Chunk{X: 3, Z: -4}
+	a, b := gen.Generate(pos, 1), gen.Generate(pos, 1)
+	other := gen.Generate(pos, 2)
+	differs := false
+	for x := 0; x < chunks.Width; x++ {
+		for z := 0; z < chunks.Depth; z++ {
+			for y := 0; y < chunks.Height; y++ {
+				if a.BlockAt(x, y, z) != b.BlockAt(x, y, z) {
+					t.Fatalf("BlockAt(%d, %d, %d) differs between two generations with the same seed", x, y, z)
+				}
+				differs = differs || a.BlockAt(x, y, z) != other.BlockAt(x, y, z)
+			}
+		}
+	}
+	if !differs {
+		t.Errorf("chunks generated with seeds 1 and 2 are the same")
+	}
+}
@@ -0,0 +1,260 @@
+package worlds
+
+import (
+	"bytes"
+	"container/list"
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	log "github.com/sirupsen/logrus"
+	"runtime"
+)
+
+// Chunks are loaded lazily: a chunk is read from the store or generated the first time it is requested, on one of the
+// dimension's workers so slow generation doesn't hold up the players. Players Retain the chunks they have in view and
+// Release them when they move away. Chunks that nobody retains stay cached in case they are needed again, until there
+// are more than MaxUnusedChunks of them and the least recently used ones are saved and evicted.
+
+const DefaultMaxUnusedChunks = 1024
+
+// cachedChunk is a loaded chunk, together with its position in the unused list if nobody has it in view
+type cachedChunk struct {
+	column *chunks.ChunkColumn
+	unused *list.Element
+}
+
+// ChunkCallback is called with the chunk once it is ready. It runs on a worker goroutine, so it shouldn't block.
+type ChunkCallback func(chunk *chunks.ChunkColumn)
+
+// ChunkAt returns the chunk at p if it is loaded, or nil otherwise. Use RequestChunk or Chunk to load it.
+func (w *Dimension) ChunkAt(p pstn.Chunk) *chunks.ChunkColumn {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if c, ok := w.chunks[p]; ok {
+		return c.column
+	}
+	return nil
+}
+
+func (w *Dimension) ChunkAtBlock(p pstn.Block) *chunks.ChunkColumn {
+	return w.ChunkAt(pstn.BlockToChunk(p))
+}
+
+// RequestChunk loads or generates the chunk at p in the background and calls done with it when it's ready. If the
+// chunk is already loaded, done is called right away on the caller's goroutine. Once the dimension is closed, chunks
+// that aren't loaded are never ready, see Done.
+func (w *Dimension) RequestChunk(p pstn.Chunk, done ChunkCallback) {
+	w.mu.Lock()
+	if c, ok := w.chunks[p]; ok {
+		w.mu.Unlock()
+		done(c.column)
+		return
+	}
+	if chunk, ok := w.saving[p]; ok {
+		// It's being saved after it was evicted, so the store doesn't have its latest version yet
+		w.cacheChunk(chunk)
+		w.mu.Unlock()
+		done(chunk)
+		return
+	}
+
+	waiting, loading := w.pending[p]
+	w.pending[p] = append(waiting, done)
+	w.mu.Unlock()
+	if !loading {
+		w.jobsMu.RLock()
+		defer w.jobsMu.RUnlock()
+		select {
+		case <-w.closed:
+		default:
+			w.jobs <- p
+		}
+	}
+}
+
+// Chunk returns the chunk at p, loading or generating it first if needed. It blocks until the chunk is ready.
+func (w *Dimension) Chunk(p pstn.Chunk) *chunks.ChunkColumn {
+	ready := make(chan *chunks.ChunkColumn, 1)
+	w.RequestChunk(p, func(chunk *chunks.ChunkColumn) {
+		ready <- chunk
+	})
+	return <-ready
+}
+
+// LoadChunk adds a chunk that was created outside of the dimension, replacing the one at its position
+func (w *Dimension) LoadChunk(chunk *chunks.ChunkColumn) {
+	chunk.TrackChanges()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if old, ok := w.chunks[chunk.Pos]; ok && old.unused != nil {
+		w.unused.Remove(old.unused)
+	}
+	delete(w.chunks, chunk.Pos)
+	w.cacheChunk(chunk)
+}
+
+// Retain marks the chunk at p as in use so it isn't evicted. Every Retain must be matched by a Release. The chunk
+// doesn't have to be loaded yet.
+func (w *Dimension) Retain(p pstn.Chunk) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.refs[p]++
+	if c, ok := w.chunks[p]; ok && c.unused != nil {
+		w.unused.Remove(c.unused)
+		c.unused = nil
+	}
+}
+
+// Release undoes a Retain. Once nothing retains the chunk anymore it may be evicted.
+func (w *Dimension) Release(p pstn.Chunk) {
+	w.mu.Lock()
+	w.refs[p]--
+	if w.refs[p] > 0 {
+		w.mu.Unlock()
+		return
+	}
+	if w.refs[p] < 0 {
+		w.mu.Unlock()
+		panic("worlds: chunk released more often than it was retained")
+	}
+	delete(w.refs, p)
+	if c, ok := w.chunks[p]; ok {
+		c.unused = w.unused.PushFront(p)
+	}
+	evicted := w.evict()
+	w.mu.Unlock()
+	w.saveEvicted(evicted)
+}
+
+// LoadedChunks returns the number of chunks in memory, including those that nobody has in view
+func (w *Dimension) LoadedChunks() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.chunks)
+}
+
+// cacheChunk adds a newly loaded chunk to the cache. w.mu must be held.
+func (w *Dimension) cacheChunk(chunk *chunks.ChunkColumn) {
+	c := &cachedChunk{column: chunk}
+	if w.refs[chunk.Pos] == 0 {
+		c.unused = w.unused.PushFront(chunk.Pos)
+	}
+	w.chunks[chunk.Pos] = c
+}
+
+// evict removes the least recently used chunks until there are at most MaxUnusedChunks that nobody retains, and
+// returns the ones that have to be saved. w.mu must be held.
+func (w *Dimension) evict() []*chunks.ChunkColumn {
+	var evicted []*chunks.ChunkColumn
+	for w.unused.Len() > w.MaxUnusedChunks {
+		p := w.unused.Remove(w.unused.Back()).(pstn.Chunk)
+		c := w.chunks[p]
+		c.unused = nil
+		if !c.column.Dirty() {
+			delete(w.chunks, p)
+			continue
+		}
+		if w.Store == nil {
+			// Without a store the changes would be lost, so changed chunks stay in memory for good
+			continue
+		}
+		delete(w.chunks, p)
+		w.saving[p] = c.column
+		evicted = append(evicted, c.column)
+	}
+	return evicted
+}
+
+func (w *Dimension) saveEvicted(evicted []*chunks.ChunkColumn) {
+	for _, chunk := range evicted {
+		if err := w.Store.SaveChunk(chunk); err != nil {
+			log.Errorf("failed to save evicted chunk %v: %v", chunk.Pos, err)
+		}
+		w.mu.Lock()
+		if w.saving[chunk.Pos] == chunk {
+			delete(w.saving, chunk.Pos)
+		}
+		w.mu.Unlock()
+	}
+}
+
+func (w *Dimension) startWorkers(n int) {
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	w.jobs = make(chan pstn.Chunk, 256)
+	w.closed = make(chan struct{})
+	w.workers.Add(n)
+	for i := 0; i < n; i++ {
+		go w.worker()
+	}
+}
+
+func (w *Dimension) worker() {
+	defer w.workers.Done()
+	for p := range w.jobs {
+		chunk := w.loadOrGenerate(p)
+		chunk.TrackChanges()
+
+		w.mu.Lock()
+		waiting := w.pending[p]
+		delete(w.pending, p)
+		if _, ok := w.chunks[p]; !ok {
+			w.cacheChunk(chunk)
+		}
+		chunk = w.chunks[p].column
+		evicted := w.evict()
+		w.mu.Unlock()
+
+		for _, done := range waiting {
+			done(chunk)
+		}
+		w.saveEvicted(evicted)
+	}
+}
+
+// Close stops the workers once they finished the chunks that were already requested. Chunks can't be requested
+// anymore afterwards.
+func (w *Dimension) Close() {
+	w.jobsMu.Lock()
+	close(w.closed)
+	close(w.jobs)
+	w.jobsMu.Unlock()
+	w.workers.Wait()
+}
+
+// Done returns a channel that is closed once the dimension is closed, after which requested chunks aren't loaded
+// anymore
+func (w *Dimension) Done() <-chan struct{} {
+	return w.closed
+}
+
+// loadOrGenerate reads the chunk from the store if it was saved before, or generates a new one
+func (w *Dimension) loadOrGenerate(p pstn.Chunk) *chunks.ChunkColumn {
+	if w.Store != nil {
+		chunk, err := w.Store.LoadChunk(p)
+		if err == nil {
+			return chunk
+		} else if err != anvil.ErrChunkNotFound {
+			log.Errorf("failed to load chunk %v, generating it instead: %v", p, err)
+		}
+	}
+	chunk := w.Generator.Generate(p, w.Seed)
+	generateLighting(chunk)
+	if w.Store == nil {
+		// Without a store there's nothing to save, and the chunk can be generated again the same way if it's evicted
+		chunk.ClearDirty()
+	}
+	return chunk
+}
+
+var allLight = bytes.Repeat([]byte{15}, chunks.BlocksInSection)
+
+// generateLighting lights a newly generated chunk as if it was all open sky
+// TODO: Don't send every chunk as fully lit
+func generateLighting(chunk *chunks.ChunkColumn) {
+	for i := range chunk.Sections {
+		chunk.Sections[i].Lighting.SkyLight.Init(allLight)
+	}
+	chunk.SkySection.SkyLight.Init(allLight)
+}
@@ -0,0 +1,154 @@
+package worlds
+
+import (
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingGenerator is a flat stone world that counts how many chunks it generated
+type countingGenerator struct {
+	generated int32
+}
+
+func (g *countingGenerator) Generate(pos pstn.Chunk, seed int64) *chunks.ChunkColumn {
+	atomic.AddInt32(&g.generated, 1)
+	chunk := chunks.NewChunk(pos)
+	for x := 0; x < chunks.Width; x++ {
+		for z := 0; z < chunks.Depth; z++ {
+			chunk.SetBlockAt(x, 0, z, blocks.Stone)
+		}
+	}
+	return chunk
+}
+
+func TestDimension_RequestChunk(t *testing.T) {
+	gen := &countingGenerator{}
+	w := New(Config{Generator: gen, Workers: 4})
+	defer w.Close()
+
+	p := pstn.Chunk{X: 3, Z: -7}
+	if w.ChunkAt(p) != nil {
+		t.Fatalf("ChunkAt(%v) before requesting = loaded, want nil", p)
+	}
+
+	var wg sync.WaitGroup
+	got := make([]*chunks.ChunkColumn, 16)
+	for i := range got {
+		wg.Add(1)
+		i := i
+		w.RequestChunk(p, func(chunk *chunks.ChunkColumn) {
+			got[i] = chunk
+			wg.Done()
+		})
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&gen.generated) != 1 {
+		t.Errorf("generated %d chunks for the same position, want 1", gen.generated)
+	}
+	for i := range got {
+		if got[i] != got[0] || got[i].Pos != p {
+			t.Fatalf("request %d got chunk %p at %v, want %p at %v", i, got[i], got[i].Pos, got[0], p)
+		}
+	}
+	if w.ChunkAt(p) != got[0] {
+		t.Errorf("ChunkAt(%v) after loading = %p, want %p", p, w.ChunkAt(p), got[0])
+	}
+	if w.BlockAt(pstn.Block{X: 3 * 16, Y: 0, Z: -7 * 16}) != blocks.Stone {
+		t.Errorf("BlockAt in generated chunk isn't stone")
+	}
+}
+
+func TestDimension_RequestChunkAfterClose(t *testing.T) {
+	w := New(Config{Workers: 1})
+	w.Close()
+	select {
+	case <-w.Done():
+	default:
+		t.Fatalf("Done() isn't closed after Close()")
+	}
+	w.RequestChunk(pstn.Chunk{}, func(chunk *chunks.ChunkColumn) {
+		t.Errorf("chunk was loaded after Close()")
+	})
+}
+
+func TestDimension_Eviction(t *testing.T) {
+	gen := &countingGenerator{}
+	w := New(Config{Generator: gen, Workers: 2, MaxUnusedChunks: 2})
+	defer w.Close()
+
+	viewed := pstn.Chunk{X: 0, Z: 0}
+	w.Retain(viewed)
+	w.Chunk(viewed)
+	for x := int32(1); x <= 4; x++ {
+		w.Chunk(pstn.Chunk{X: x})
+	}
+
+	if n := w.LoadedChunks(); n != 3 {
+		t.Errorf("LoadedChunks() = %d, want the retained chunk and 2 unused ones", n)
+	}
+	if w.ChunkAt(viewed) == nil {
+		t.Errorf("retained chunk was evicted")
+	}
+	if w.ChunkAt(pstn.Chunk{X: 1}) != nil || w.ChunkAt(pstn.Chunk{X: 4}) == nil {
+		t.Errorf("evicted chunks aren't the least recently used ones")
+	}
+
+	// Once it's released it's the most recently used, so the oldest of the others goes
+	w.Release(viewed)
+	if w.ChunkAt(viewed) == nil || w.ChunkAt(pstn.Chunk{X: 3}) != nil {
+		t.Errorf("releasing a chunk evicted the wrong chunk")
+	}
+}
+
+func TestDimension_EvictionKeepsChanges(t *testing.T) {
+	t.Run("in memory", func(t *testing.T) {
+		w := New(Config{Generator: &countingGenerator{}, MaxUnusedChunks: 1})
+		defer w.Close()
+
+		w.Chunk(pstn.Chunk{X: 0})
+		w.SetBlockAt(pstn.Block{X: 1, Y: 1, Z: 1}, blocks.Glass)
+		w.Chunk(pstn.Chunk{X: 1})
+		w.Chunk(pstn.Chunk{X: 2})
+		if w.BlockAt(pstn.Block{X: 1, Y: 1, Z: 1}) != blocks.Glass {
+			t.Errorf("changed chunk of a world without a store was evicted")
+		}
+	})
+
+	t.Run("with store", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "worlds")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		store := anvil.Open(dir)
+		defer store.Close()
+
+		gen := &countingGenerator{}
+		w := New(Config{Generator: gen, Store: store, MaxUnusedChunks: 1})
+		defer w.Close()
+
+		w.Chunk(pstn.Chunk{X: 0})
+		w.SetBlockAt(pstn.Block{X: 1, Y: 1, Z: 1}, blocks.Glass)
+		w.Chunk(pstn.Chunk{X: 1})
+		w.Chunk(pstn.Chunk{X: 2})
+		if w.ChunkAt(pstn.Chunk{X: 0}) != nil {
+			t.Fatalf("least recently used chunk wasn't evicted")
+		}
+
+		chunk := w.Chunk(pstn.Chunk{X: 0})
+		if chunk.BlockAt(1, 1, 1) != blocks.Glass {
+			t.Errorf("evicted chunk lost its changes")
+		}
+		if atomic.LoadInt32(&gen.generated) != 3 {
+			t.Errorf("generated %d chunks, want 3 since the evicted one is loaded from the store", gen.generated)
+		}
+	})
+}
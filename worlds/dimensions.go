@@ -1,10 +1,14 @@
 package worlds
 
 import (
-	"bytes"
+	"container/list"
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/biome"
 	"github.com/masp/mcgo/blocks"
 	"github.com/masp/mcgo/chunks"
 	"github.com/masp/mcgo/pstn"
+	log "github.com/sirupsen/logrus"
+	"sync"
 )
 
 type Type int
@@ -15,67 +19,284 @@ const (
 	End       Type = 1
 )
 
+// Name returns the namespaced ID of the dimension type in the registry sent to clients
+func (t Type) Name() string {
+	switch t {
+	case Nether:
+		return "minecraft:the_nether"
+	case End:
+		return "minecraft:the_end"
+	default:
+		return "minecraft:overworld"
+	}
+}
+
+// Def returns how clients should render dimensions of the type
+func (t Type) Def() biome.DimensionDef {
+	switch t {
+	case Nether:
+		return biome.NetherDimension()
+	case End:
+		return biome.EndDimension()
+	default:
+		return biome.OverworldDimension()
+	}
+}
+
+// SaveDir returns the directory vanilla saves dimensions of the type in, relative to the world directory
+func (t Type) SaveDir() string {
+	switch t {
+	case Nether:
+		return "DIM-1"
+	case End:
+		return "DIM1"
+	default:
+		return ""
+	}
+}
+
+// TypeByName returns the type with the given name, see Type.Name
+func TypeByName(name string) (Type, bool) {
+	for _, t := range []Type{Overworld, Nether, End} {
+		if t.Name() == name {
+			return t, true
+		}
+	}
+	return Overworld, false
+}
+
+func (t Type) defaultPreset() string {
+	switch t {
+	case Nether:
+		return DefaultNetherPreset
+	case End:
+		return DefaultEndPreset
+	default:
+		return DefaultFlatPreset
+	}
+}
+
+// Viewer is anything that is shown the chunks of a dimension and needs to be told when they change, like a player
+type Viewer interface {
+	ViewsChunk(pos pstn.Chunk) bool
+	SendChunkChanges(changes *chunks.ColumnChanges)
+}
+
+// Config is everything needed to create a dimension
+type Config struct {
+	// Name identifies the dimension to clients, it's the name of its type if empty
+	Name  string
+	Type  Type
+	Spawn pstn.Block
+	Seed  int64
+	// Generator creates chunks that aren't in the store, it's a flat world of the default preset of the type if nil
+	Generator Generator
+	// Store is where chunks are loaded from before they are generated, it's nil for worlds that only live in memory
+	Store *anvil.Store
+	// Workers is the number of goroutines loading and generating chunks, one per CPU if 0
+	Workers int
+	// MaxUnusedChunks is how many chunks nobody has in view are kept in memory, DefaultMaxUnusedChunks if 0
+	MaxUnusedChunks int
+	// GameRules change the defaults of DefaultGameRules
+	GameRules GameRules
+}
+
+// Dimension is a world of chunks that players can be in. It can be used from any goroutine: the chunk cache and the
+// viewers each have their own lock, and chunks lock themselves, so players only wait on each other when they use the
+// same chunk at the same time.
 type Dimension struct {
-	Spawn  pstn.Block
-	chunks map[pstn.Chunk]*chunks.ChunkColumn
+	Name            string
+	Type            Type
+	Spawn           pstn.Block
+	Seed            int64
+	Generator       Generator
+	Store           *anvil.Store
+	MaxUnusedChunks int
+	// Scheduler runs tasks on the tick goroutine
+	Scheduler *Scheduler
+
+	mu      sync.Mutex
+	chunks  map[pstn.Chunk]*cachedChunk
+	refs    map[pstn.Chunk]int                 // how many viewers retain each chunk
+	unused  *list.List                         // chunks that nobody retains, the most recently used first
+	pending map[pstn.Chunk][]ChunkCallback     // chunks queued for the workers, with everything waiting for them
+	saving  map[pstn.Chunk]*chunks.ChunkColumn // evicted chunks that are still being saved
+	jobs    chan pstn.Chunk
+	jobsMu  sync.RWMutex  // held for sending jobs, so Close doesn't close them in between
+	closed  chan struct{} // closed by Close
+	workers sync.WaitGroup
+
+	viewersMu sync.RWMutex
+	viewers   map[Viewer]struct{}
+
+	rulesMu sync.RWMutex
+	rules   GameRules
+
+	ticks tickState
 }
 
-func New(spawn pstn.Block) Dimension {
-	w := Dimension{
-		Spawn:  spawn,
-		chunks: make(map[pstn.Chunk]*chunks.ChunkColumn),
+// New creates the dimension and starts its workers. No chunks are loaded until they are requested.
+func New(cfg Config) *Dimension {
+	w := &Dimension{
+		Name:            cfg.Name,
+		Type:            cfg.Type,
+		Spawn:           cfg.Spawn,
+		Seed:            cfg.Seed,
+		Generator:       cfg.Generator,
+		Store:           cfg.Store,
+		MaxUnusedChunks: cfg.MaxUnusedChunks,
+		Scheduler:       NewScheduler(),
+		chunks:          make(map[pstn.Chunk]*cachedChunk),
+		refs:            make(map[pstn.Chunk]int),
+		unused:          list.New(),
+		pending:         make(map[pstn.Chunk][]ChunkCallback),
+		saving:          make(map[pstn.Chunk]*chunks.ChunkColumn),
+		viewers:         make(map[Viewer]struct{}),
+		rules:           DefaultGameRules(),
+		ticks: tickState{
+			clock:    realClock{},
+			entities: make(map[Entity]struct{}),
+		},
+	}
+	if w.Name == "" {
+		w.Name = w.Type.Name()
+	}
+	if w.Generator == nil {
+		flat, err := ParseFlatPreset(w.Type.defaultPreset())
+		if err != nil {
+			panic(err)
+		}
+		w.Generator = flat
+	}
+	if w.MaxUnusedChunks == 0 {
+		w.MaxUnusedChunks = DefaultMaxUnusedChunks
 	}
-	w.generateSpawn()
+	for name, value := range cfg.GameRules {
+		w.rules[name] = value
+	}
+	w.startWorkers(cfg.Workers)
 	return w
 }
 
-func (w *Dimension) ChunkAt(p pstn.Chunk) *chunks.ChunkColumn {
-	return w.chunks[p]
+func (w *Dimension) BlockAt(p pstn.Block) chunks.BlockState {
+	chunk := w.ChunkAtBlock(p)
+	if chunk == nil {
+		return blocks.Air
+	}
+	x, y, z := pstn.BlockInChunk(p)
+	return chunk.BlockAt(x, y, z)
 }
 
-func (w *Dimension) ChunkAtBlock(p pstn.Block) *chunks.ChunkColumn {
-	return w.chunks[pstn.BlockToChunk(p)]
+// SetBlockAt changes the block at p, which is sent to viewers on the next FlushChanges. Blocks in chunks that aren't
+// loaded are ignored.
+func (w *Dimension) SetBlockAt(p pstn.Block, state chunks.BlockState) {
+	chunk := w.ChunkAtBlock(p)
+	if chunk == nil || p.Y < 0 || p.Y >= chunks.Height {
+		return
+	}
+	x, y, z := pstn.BlockInChunk(p)
+	chunk.SetBlockAt(x, y, z, state)
 }
 
-func (w *Dimension) LoadChunk(chunk *chunks.ChunkColumn) {
-	w.chunks[chunk.Pos] = chunk
+// BlockEntityAt returns the block entity at p, or nil if there is none or its chunk isn't loaded
+func (w *Dimension) BlockEntityAt(p pstn.Block) *chunks.BlockEntity {
+	chunk := w.ChunkAtBlock(p)
+	if chunk == nil {
+		return nil
+	}
+	x, y, z := pstn.BlockInChunk(p)
+	return chunk.BlockEntityAt(x, y, z)
 }
 
-func generateFlatChunk(p pstn.Chunk) *chunks.ChunkColumn {
-	chunk := chunks.NewChunk(p)
-	for y := 0; y < chunks.Height; y++ {
-		for x := 0; x < chunks.Width; x++ {
-			for z := 0; z < chunks.Depth; z++ {
-				if y < 63 {
-					chunk.SetBlockAt(x, y, z, blocks.Stone)
-				}
-			}
-		}
+// SetBlockEntity places the block entity at p, which is sent to viewers with the blocks on the next FlushChanges. The
+// block has to be set first, since changing it removes the block entity. Block entities in chunks that aren't loaded
+// are ignored.
+func (w *Dimension) SetBlockEntity(p pstn.Block, be *chunks.BlockEntity) {
+	chunk := w.ChunkAtBlock(p)
+	if chunk == nil || p.Y < 0 || p.Y >= chunks.Height {
+		return
 	}
-	return chunk
+	x, y, z := pstn.BlockInChunk(p)
+	chunk.SetBlockEntity(x, y, z, be)
 }
 
-const (
-	SpawnSize = 16 // chunks
-)
+// RemoveBlockEntity removes the block entity at p without changing the block
+func (w *Dimension) RemoveBlockEntity(p pstn.Block) {
+	chunk := w.ChunkAtBlock(p)
+	if chunk == nil || p.Y < 0 || p.Y >= chunks.Height {
+		return
+	}
+	x, y, z := pstn.BlockInChunk(p)
+	chunk.RemoveBlockEntity(x, y, z)
+}
+
+func (w *Dimension) AddViewer(v Viewer) {
+	w.viewersMu.Lock()
+	defer w.viewersMu.Unlock()
+	w.viewers[v] = struct{}{}
+}
+
+func (w *Dimension) RemoveViewer(v Viewer) {
+	w.viewersMu.Lock()
+	defer w.viewersMu.Unlock()
+	delete(w.viewers, v)
+}
 
-func (w *Dimension) generateSpawn() {
-	spawnChunk := pstn.BlockToChunk(w.Spawn)
-	for x := spawnChunk.X - SpawnSize; x <= spawnChunk.X+SpawnSize; x++ {
-		for z := spawnChunk.Z - SpawnSize; z <= spawnChunk.Z+SpawnSize; z++ {
-			p := pstn.Chunk{X: x, Z: z}
-			w.LoadChunk(generateFlatChunk(p))
+// Viewers returns everyone who is viewing the dimension
+func (w *Dimension) Viewers() []Viewer {
+	w.viewersMu.RLock()
+	defer w.viewersMu.RUnlock()
+	viewers := make([]Viewer, 0, len(w.viewers))
+	for v := range w.viewers {
+		viewers = append(viewers, v)
+	}
+	return viewers
+}
+
+// loadedColumns returns every chunk that is in memory
+func (w *Dimension) loadedColumns() []*chunks.ChunkColumn {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	columns := make([]*chunks.ChunkColumn, 0, len(w.chunks))
+	for _, c := range w.chunks {
+		columns = append(columns, c.column)
+	}
+	return columns
+}
+
+// FlushChanges sends every block and block entity that changed since the last flush to the viewers that can see it
+func (w *Dimension) FlushChanges() {
+	for _, chunk := range w.loadedColumns() {
+		changes := chunk.TakeChanges()
+		if changes == nil {
+			continue
+		}
+		w.viewersMu.RLock()
+		for v := range w.viewers {
+			if v.ViewsChunk(chunk.Pos) {
+				v.SendChunkChanges(changes)
+			}
 		}
+		w.viewersMu.RUnlock()
 	}
-	w.generateLighting()
 }
 
-func (w *Dimension) generateLighting() {
-	allLight := bytes.Repeat([]byte{15}, chunks.BlocksInSection)
-	for _, chunk := range w.chunks {
-		for _, sec := range chunk.Sections {
-			sec.Lighting.SkyLight.Init(allLight)
+// Save writes every chunk that changed since it was last saved to the store. It does nothing for dimensions without a
+// store.
+func (w *Dimension) Save() error {
+	if w.Store == nil {
+		return nil
+	}
+	saved := 0
+	for _, chunk := range w.loadedColumns() {
+		if !chunk.Dirty() {
+			continue
+		}
+		if err := w.Store.SaveChunk(chunk); err != nil {
+			return err
 		}
-		chunk.SkySection.SkyLight.Init(allLight)
+		saved++
 	}
+	log.Infof("Saved %d chunks", saved)
+	return nil
 }
@@ -0,0 +1,232 @@
+package worlds
+
+import (
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/biome"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/proto"
+	"github.com/masp/mcgo/pstn"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePlayer views a square of chunks and encodes every change it's sent, like a real player would
+type fakePlayer struct {
+	mu      sync.Mutex
+	center  pstn.Chunk
+	changes int
+}
+
+const fakeViewDistance = 1
+
+func (f *fakePlayer) ViewsChunk(pos pstn.Chunk) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return pos.X >= f.center.X-fakeViewDistance && pos.X <= f.center.X+fakeViewDistance &&
+		pos.Z >= f.center.Z-fakeViewDistance && pos.Z <= f.center.Z+fakeViewDistance
+}
+
+func (f *fakePlayer) SendChunkChanges(changes *chunks.ColumnChanges) {
+	f.mu.Lock()
+	f.changes++
+	f.mu.Unlock()
+	for y, changed := range changes.Sections {
+		if len(changed) > 0 {
+			proto.EncodePacket(0, chunks.MultiBlockChangePacket{Chunk: changes.Chunk, SectionY: y, Blocks: changed})
+		}
+	}
+}
+
+func (f *fakePlayer) moveTo(center pstn.Chunk) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.center = center
+}
+
+func viewSquare(center pstn.Chunk) []pstn.Chunk {
+	var square []pstn.Chunk
+	for x := center.X - fakeViewDistance; x <= center.X+fakeViewDistance; x++ {
+		for z := center.Z - fakeViewDistance; z <= center.Z+fakeViewDistance; z++ {
+			square = append(square, pstn.Chunk{X: x, Z: z})
+		}
+	}
+	return square
+}
+
+// play walks around the dimension while placing blocks, and leaves and joins again after every step
+func (f *fakePlayer) play(w *Dimension, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	center := pstn.Chunk{X: int32(rng.Intn(6)), Z: int32(rng.Intn(6))}
+	for step := 0; step < 6; step++ {
+		w.AddViewer(f)
+		f.moveTo(center)
+		view := viewSquare(center)
+		var loaded sync.WaitGroup
+		for _, p := range view {
+			w.Retain(p)
+			loaded.Add(1)
+			w.RequestChunk(p, func(chunk *chunks.ChunkColumn) {
+				proto.EncodePacket(0, chunk)
+				loaded.Done()
+			})
+		}
+		loaded.Wait()
+
+		for i := 0; i < 20; i++ {
+			p := pstn.Block{
+				X: center.X*16 + int32(rng.Intn(16)),
+				Y: int32(rng.Intn(chunks.Height)),
+				Z: center.Z*16 + int32(rng.Intn(16)),
+			}
+			w.SetBlockAt(p, blocks.Glass)
+			w.BlockAt(p)
+		}
+
+		for _, p := range view {
+			w.Release(p)
+		}
+		w.RemoveViewer(f)
+		center.X += int32(rng.Intn(3) - 1)
+		center.Z += int32(rng.Intn(3) - 1)
+	}
+}
+
+// TestDimension_Concurrent has players join, load chunks and change blocks at the same time as the dimension is
+// flushed and saved, with a cache small enough that chunks are evicted all the time. It's meant to be run with -race.
+func TestDimension_Concurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "worlds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := anvil.Open(dir)
+	defer store.Close()
+
+	w := New(Config{Generator: &countingGenerator{}, Store: store, Workers: 4, MaxUnusedChunks: 4})
+	defer w.Close()
+
+	stop := make(chan struct{})
+	var background sync.WaitGroup
+	background.Add(2)
+	go func() {
+		defer background.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				w.FlushChanges()
+			}
+		}
+	}()
+	go func() {
+		defer background.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Millisecond):
+				if err := w.Save(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}
+	}()
+
+	var players sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		players.Add(1)
+		go func(seed int64) {
+			defer players.Done()
+			(&fakePlayer{}).play(w, seed)
+		}(int64(i))
+	}
+	players.Wait()
+	close(stop)
+	background.Wait()
+
+	// Every changed chunk is either still in memory or was saved when it was evicted
+	if err := w.Save(); err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range w.loadedColumns() {
+		if chunk.Dirty() {
+			t.Errorf("chunk %v is still dirty after saving", chunk.Pos)
+		}
+	}
+}
+
+func TestNew_DefaultsForType(t *testing.T) {
+	tests := []struct {
+		t       Type
+		top     int
+		surface chunks.BlockState
+		biome   int32
+	}{
+		{Overworld, 62, blocks.Stone, biome.PlainsID},
+		{Nether, 63, blocks.Netherrack, biome.NetherWastesID},
+		{End, 63, blocks.EndStone, biome.TheEndID},
+	}
+	for _, tt := range tests {
+		w := New(Config{Type: tt.t})
+		if w.Name != tt.t.Name() {
+			t.Errorf("dimension of type %d is named %q, want %q", tt.t, w.Name, tt.t.Name())
+		}
+		chunk := w.Chunk(pstn.Chunk{})
+		if got := chunk.BlockAt(0, tt.top, 0); got != tt.surface {
+			t.Errorf("%s has %v on the surface, want %v", w.Name, got, tt.surface)
+		}
+		if got := chunk.BlockAt(0, tt.top+1, 0); got != blocks.Air {
+			t.Errorf("%s has %v above the surface, want air", w.Name, got)
+		}
+		if got := chunk.BiomeAt(0, tt.top, 0); got != tt.biome {
+			t.Errorf("%s has biome %d, want %d", w.Name, got, tt.biome)
+		}
+		w.Close()
+	}
+}
+
+// changesViewer keeps the changes it's sent
+type changesViewer struct {
+	sent []*chunks.ColumnChanges
+}
+
+func (v *changesViewer) ViewsChunk(pos pstn.Chunk) bool { return true }
+
+func (v *changesViewer) SendChunkChanges(changes *chunks.ColumnChanges) {
+	v.sent = append(v.sent, changes)
+}
+
+func TestDimension_SetBlockEntity(t *testing.T) {
+	w := New(Config{Workers: 1})
+	defer w.Close()
+	w.Chunk(pstn.Chunk{})
+	viewer := &changesViewer{}
+	w.AddViewer(viewer)
+
+	pos := pstn.Block{X: 3, Y: 70, Z: 4}
+	w.SetBlockAt(pos, blocks.OakSign)
+	w.SetBlockEntity(pos, chunks.NewSign("hello"))
+	w.FlushChanges()
+	if len(viewer.sent) != 1 || len(viewer.sent[0].BlockEntities) != 1 {
+		t.Fatalf("viewer was sent %v, want the sign with the block", viewer.sent)
+	}
+	if got := viewer.sent[0].BlockEntities[0]; got.Pos != pos || got.ID != "minecraft:sign" {
+		t.Errorf("viewer was sent block entity %s at %v, want the sign at %v", got.ID, got.Pos, pos)
+	}
+
+	w.SetBlockAt(pos, blocks.Air)
+	if got := w.BlockEntityAt(pos); got != nil {
+		t.Errorf("BlockEntityAt() after breaking the block = %v, want nil", got)
+	}
+	w.FlushChanges()
+	if last := viewer.sent[len(viewer.sent)-1]; len(last.BlockEntities) != 0 {
+		t.Errorf("viewer was sent block entities %v of a broken block", last.BlockEntities)
+	}
+}
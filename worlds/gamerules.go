@@ -0,0 +1,62 @@
+package worlds
+
+import (
+	"strconv"
+)
+
+// GameRules are the values of /gamerule in a world. Like in level.dat, every value is a string.
+type GameRules map[string]string
+
+// DefaultGameRules returns the rules of a new vanilla world
+func DefaultGameRules() GameRules {
+	return GameRules{
+		"announceAdvancements":     "true",
+		"commandBlockOutput":       "true",
+		"doDaylightCycle":          "true",
+		"doFireTick":               "true",
+		"doMobSpawning":            "true",
+		"doWeatherCycle":           "true",
+		"keepInventory":            "false",
+		"maxEntityCramming":        "24",
+		"mobGriefing":              "true",
+		"naturalRegeneration":      "true",
+		"randomTickSpeed":          "3",
+		"showDeathMessages":        "true",
+		"spawnRadius":              "10",
+		"spectatorsGenerateChunks": "true",
+	}
+}
+
+// Bool returns the value of a boolean rule, false if it isn't set
+func (r GameRules) Bool(name string) bool {
+	return r[name] == "true"
+}
+
+// Int returns the value of an integer rule, 0 if it isn't set
+func (r GameRules) Int(name string) int {
+	n, _ := strconv.Atoi(r[name])
+	return n
+}
+
+// GameRules returns a copy of the rules of the dimension
+func (w *Dimension) GameRules() GameRules {
+	w.rulesMu.RLock()
+	defer w.rulesMu.RUnlock()
+	rules := make(GameRules, len(w.rules))
+	for name, value := range w.rules {
+		rules[name] = value
+	}
+	return rules
+}
+
+func (w *Dimension) GameRule(name string) string {
+	w.rulesMu.RLock()
+	defer w.rulesMu.RUnlock()
+	return w.rules[name]
+}
+
+func (w *Dimension) SetGameRule(name string, value string) {
+	w.rulesMu.Lock()
+	defer w.rulesMu.Unlock()
+	w.rules[name] = value
+}
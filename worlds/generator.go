@@ -0,0 +1,152 @@
+package worlds
+
+import (
+	"fmt"
+	"github.com/masp/mcgo/biome"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	"github.com/masp/mcgo/terrain"
+	"strconv"
+	"strings"
+)
+
+// Generator creates the chunks of a dimension that haven't been generated before. Generate must always return the
+// same chunk for the same position and seed.
+type Generator interface {
+	Generate(pos pstn.Chunk, seed int64) *chunks.ChunkColumn
+}
+
+// DefaultFlatPreset is the flat world the server generates when no other generator is configured
+const DefaultFlatPreset = "63*minecraft:stone"
+
+// Flat presets for the nether and the end when they have no other generator
+const (
+	DefaultNetherPreset = "minecraft:bedrock,63*minecraft:netherrack;minecraft:nether_wastes"
+	DefaultEndPreset    = "56*minecraft:air,8*minecraft:end_stone;minecraft:the_end"
+)
+
+// ParseGenerator creates a generator from a short description that can be stored in a config or level.dat:
+//
+//	flat:<preset>  a flat world of a preset, see ParseFlatPreset
+//	flat           a flat world of DefaultFlatPreset
+//	terrain        natural terrain, see the terrain package
+//
+// An empty description means the default generator of the dimension type, for which nil is returned.
+func ParseGenerator(description string) (Generator, error) {
+	name, options := description, ""
+	if i := strings.Index(description, ":"); i >= 0 {
+		name, options = description[:i], description[i+1:]
+	}
+	switch name {
+	case "":
+		return nil, nil
+	case "flat":
+		if options == "" {
+			options = DefaultFlatPreset
+		}
+		flat, err := ParseFlatPreset(options)
+		if err != nil {
+			return nil, err
+		}
+		return flat, nil
+	case "terrain":
+		return terrain.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown generator %q", name)
+	}
+}
+
+// FlatGenerator generates a superflat world of the same layers everywhere
+type FlatGenerator struct {
+	Layers []chunks.BlockState // from the bottom of the world up
+	Biome  int32
+}
+
+// ParseFlatPreset creates a flat generator from a vanilla superflat preset string, which is a comma separated list of
+// layers from the bottom up with an optional biome after a semicolon, e.g.
+//
+//	minecraft:bedrock,3*minecraft:dirt,minecraft:grass_block;minecraft:plains
+//
+// Anything after the biome (like structures) is ignored.
+func ParseFlatPreset(preset string) (*FlatGenerator, error) {
+	parts := strings.Split(preset, ";")
+	gen := &FlatGenerator{Biome: biome.PlainsID}
+	for _, layer := range strings.Split(parts[0], ",") {
+		layer = strings.TrimSpace(layer)
+		count := 1
+		if i := strings.Index(layer, "*"); i >= 0 {
+			n, err := strconv.Atoi(layer[:i])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("flat preset: invalid layer height in %q", layer)
+			}
+			count, layer = n, layer[i+1:]
+		}
+		state, err := blocks.Get(layer, nil)
+		if err != nil {
+			return nil, fmt.Errorf("flat preset: %w", err)
+		}
+		// checked before appending, so a huge count doesn't allocate the layers first
+		if height := len(gen.Layers) + count; height > chunks.Height {
+			return nil, fmt.Errorf("flat preset: %d layers is higher than the world", height)
+		}
+		for i := 0; i < count; i++ {
+			gen.Layers = append(gen.Layers, state)
+		}
+	}
+
+	if len(parts) > 1 && parts[1] != "" {
+		id, ok := biome.IDByName(parts[1])
+		if !ok {
+			return nil, fmt.Errorf("flat preset: unknown biome %s", parts[1])
+		}
+		gen.Biome = id
+	}
+	return gen, nil
+}
+
+// VanillaGenerator returns the generator as a minecraft:flat generator compound of level.dat, so vanilla generates the
+// same layers
+func (g *FlatGenerator) VanillaGenerator() map[string]interface{} {
+	var layers []interface{}
+	for i := 0; i < len(g.Layers); {
+		height := 1
+		for i+height < len(g.Layers) && g.Layers[i+height] == g.Layers[i] {
+			height++
+		}
+		name, _, ok := blocks.Lookup(g.Layers[i])
+		if !ok {
+			name = "minecraft:air"
+		}
+		layers = append(layers, map[string]interface{}{"block": name, "height": int32(height)})
+		i += height
+	}
+	biomeName, ok := biome.NameByID(g.Biome)
+	if !ok {
+		biomeName = "minecraft:plains"
+	}
+	return map[string]interface{}{
+		"type": "minecraft:flat",
+		"settings": map[string]interface{}{
+			"layers":     layers,
+			"biome":      biomeName,
+			"structures": map[string]interface{}{"structures": map[string]interface{}{}},
+		},
+	}
+}
+
+func (g *FlatGenerator) Generate(pos pstn.Chunk, seed int64) *chunks.ChunkColumn {
+	chunk := chunks.NewChunk(pos)
+	chunk.FillBiome(g.Biome)
+	for y, state := range g.Layers {
+		if state == blocks.Air {
+			continue
+		}
+		for x := 0; x < chunks.Width; x++ {
+			for z := 0; z < chunks.Depth; z++ {
+				chunk.SetBlockAt(x, y, z, state)
+			}
+		}
+	}
+	return chunk
+}
@@ -0,0 +1,97 @@
+package worlds
+
+import (
+	"github.com/masp/mcgo/biome"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	"reflect"
+	"testing"
+)
+
+func TestParseFlatPreset(t *testing.T) {
+	tests := []struct {
+		preset string
+		want   []chunks.BlockState
+	}{
+		{"minecraft:bedrock,3*minecraft:dirt,minecraft:grass_block",
+			[]chunks.BlockState{blocks.Bedrock, blocks.Dirt, blocks.Dirt, blocks.Dirt, blocks.GrassBlock}},
+		{"minecraft:stone;minecraft:plains;village", []chunks.BlockState{blocks.Stone}},
+		{" minecraft:air , 2*minecraft:sand", []chunks.BlockState{blocks.Air, blocks.Sand, blocks.Sand}},
+	}
+	for _, tt := range tests {
+		gen, err := ParseFlatPreset(tt.preset)
+		if err != nil {
+			t.Errorf("ParseFlatPreset(%q) error = %v", tt.preset, err)
+			continue
+		}
+		if !reflect.DeepEqual(gen.Layers, tt.want) {
+			t.Errorf("ParseFlatPreset(%q) layers = %v, want %v", tt.preset, gen.Layers, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"minecraft:unknown", "0*minecraft:stone", "x*minecraft:stone", "minecraft:stone;minecraft:nowhere", "257*minecraft:stone",
+		"2000000000*minecraft:stone", "200*minecraft:stone,100*minecraft:dirt"} {
+		if _, err := ParseFlatPreset(invalid); err == nil {
+			t.Errorf("ParseFlatPreset(%q) error = nil, want error", invalid)
+		}
+	}
+}
+
+func TestParseGenerator(t *testing.T) {
+	if gen, err := ParseGenerator(""); gen != nil || err != nil {
+		t.Errorf("ParseGenerator(\"\") = %v, %v, want nil, nil", gen, err)
+	}
+	if gen, err := ParseGenerator("flat"); err != nil || gen.(*FlatGenerator).Layers[0] != blocks.Stone {
+		t.Errorf("ParseGenerator(flat) = %v, %v, want the default preset", gen, err)
+	}
+	if gen, err := ParseGenerator("flat:minecraft:sand"); err != nil || gen.(*FlatGenerator).Layers[0] != blocks.Sand {
+		t.Errorf("ParseGenerator(flat:minecraft:sand) = %v, %v, want sand", gen, err)
+	}
+	if _, err := ParseGenerator("terrain"); err != nil {
+		t.Errorf("ParseGenerator(terrain) error = %v", err)
+	}
+	for _, invalid := range []string{"flat:minecraft:unknown", "amplified"} {
+		if gen, err := ParseGenerator(invalid); gen != nil || err == nil {
+			t.Errorf("ParseGenerator(%q) = %v, %v, want an error", invalid, gen, err)
+		}
+	}
+}
+
+func TestFlatGenerator_Generate(t *testing.T) {
+	gen, err := ParseFlatPreset("minecraft:bedrock,3*minecraft:dirt,minecraft:grass_block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := gen.Generate(pstn.Chunk{X: 2, Z: -9}, 0)
+	for y, want := range []chunks.BlockState{blocks.Bedrock, blocks.Dirt, blocks.Dirt, blocks.Dirt, blocks.GrassBlock, blocks.Air} {
+		if got := chunk.BlockAt(5, y, 11); got != want {
+			t.Errorf("BlockAt(5, %d, 11) = %d, want %d", y, got, want)
+		}
+	}
+	if got := chunk.BiomeAt(0, 0, 0); got != biome.PlainsID {
+		t.Errorf("BiomeAt(0, 0, 0) = %d, want plains", got)
+	}
+}
+
+func TestFlatGenerator_VanillaGenerator(t *testing.T) {
+	gen, err := ParseFlatPreset("minecraft:bedrock,3*minecraft:dirt,minecraft:grass_block;minecraft:desert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"type": "minecraft:flat",
+		"settings": map[string]interface{}{
+			"layers": []interface{}{
+				map[string]interface{}{"block": "minecraft:bedrock", "height": int32(1)},
+				map[string]interface{}{"block": "minecraft:dirt", "height": int32(3)},
+				map[string]interface{}{"block": "minecraft:grass_block", "height": int32(1)},
+			},
+			"biome":      "minecraft:desert",
+			"structures": map[string]interface{}{"structures": map[string]interface{}{}},
+		},
+	}
+	if got := gen.VanillaGenerator(); !reflect.DeepEqual(got, want) {
+		t.Errorf("VanillaGenerator() = %v, want %v", got, want)
+	}
+}
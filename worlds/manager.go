@@ -0,0 +1,392 @@
+package worlds
+
+import (
+	"errors"
+	"fmt"
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/pstn"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+var (
+	ErrWorldExists   = errors.New("world already exists")
+	ErrWorldNotFound = errors.New("world not found")
+	ErrNoOtherWorld  = errors.New("no other world to move players to")
+)
+
+// worldNames are the names a world can have. They are used as directory names and as the name of the world on
+// clients, so they are limited to what is valid in both.
+var worldNames = regexp.MustCompile(`^[a-z0-9_.-]+$`)
+
+// WorldSettings describe a world that the Manager hosts. They are kept in the level.dat of the world.
+type WorldSettings struct {
+	Name string
+	Type Type
+	// Generator describes how chunks are generated, see ParseGenerator
+	Generator string
+	Seed      int64
+	// Spawn is where players appear in the world. If it's the zero block, new worlds put it on top of the highest
+	// block at x 0 z 0.
+	Spawn pstn.Block
+	// GameRules change the defaults of DefaultGameRules
+	GameRules GameRules
+	// GameType is the gamemode vanilla gives new players when it opens the world
+	GameType int32
+}
+
+// Traveler is anything that can be moved between worlds, like a player
+type Traveler interface {
+	World() *Dimension
+	Teleport(pos pstn.Entity)
+	ChangeDimension(to *Dimension, pos pstn.Entity)
+}
+
+// Manager hosts named worlds at the same time, like lobbies and minigame arenas next to the main world. Every world
+// is saved in a directory named after it with its own level.dat, and ticks on its own goroutine. Worlds can be
+// created, loaded and unloaded while players are online.
+type Manager struct {
+	dir string
+
+	mu     sync.Mutex
+	worlds []*hostedWorld // in the order they were added, players join the first one
+}
+
+type hostedWorld struct {
+	*Dimension
+	settings WorldSettings
+	time     int64 // world age read from level.dat, the ticks since are added when it's saved
+	dir      string
+	stop     chan struct{}
+	stopped  chan struct{} // closed once the tick loop returned
+}
+
+// NewManager creates a manager that saves worlds in dir. With an empty dir, worlds only live in memory.
+func NewManager(dir string) *Manager {
+	return &Manager{dir: dir}
+}
+
+// Create creates a new world and starts ticking it. It fails if a world of the same name is loaded or saved.
+func (m *Manager) Create(settings WorldSettings) (*Dimension, error) {
+	if err := m.checkNew(settings.Name); err != nil {
+		return nil, err
+	}
+	if m.dir != "" {
+		if _, err := anvil.ReadLevel(m.worldDir(settings.Name)); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrWorldExists, settings.Name)
+		}
+	}
+	w, err := m.host(settings, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.saveLevel(); err != nil {
+		m.remove(w)
+		w.shutdown()
+		return nil, err
+	}
+	return w.Dimension, nil
+}
+
+// Load loads a world that was created before from its directory and starts ticking it
+func (m *Manager) Load(name string) (*Dimension, error) {
+	if err := m.checkNew(name); err != nil {
+		return nil, err
+	}
+	if m.dir == "" {
+		return nil, fmt.Errorf("%w: %s", ErrWorldNotFound, name)
+	}
+	level, err := anvil.ReadLevel(m.worldDir(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrWorldNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	t := Overworld
+	if level.Dimension != "" {
+		var ok bool
+		if t, ok = TypeByName(level.Dimension); !ok {
+			return nil, fmt.Errorf("world %s has unknown dimension type %s", name, level.Dimension)
+		}
+	}
+	w, err := m.host(WorldSettings{
+		Name:      name,
+		Type:      t,
+		Generator: level.Generator,
+		Seed:      level.Seed,
+		Spawn:     level.Spawn,
+		GameRules: level.GameRules,
+		GameType:  level.GameType,
+	}, level.Time)
+	if err != nil {
+		return nil, err
+	}
+	return w.Dimension, nil
+}
+
+// LoadOrCreate loads the world of the same name if it was saved before, and creates it otherwise
+func (m *Manager) LoadOrCreate(settings WorldSettings) (*Dimension, error) {
+	w, err := m.Load(settings.Name)
+	if errors.Is(err, ErrWorldNotFound) {
+		return m.Create(settings)
+	}
+	return w, err
+}
+
+// Unload saves a world and stops it. Players that are still in it are moved to the spawn of the first world.
+func (m *Manager) Unload(name string) error {
+	m.mu.Lock()
+	w := m.find(name)
+	if w == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrWorldNotFound, name)
+	}
+	var fallback *Dimension
+	for _, other := range m.worlds {
+		if other != w {
+			fallback = other.Dimension
+			break
+		}
+	}
+	if fallback == nil && len(w.Viewers()) > 0 {
+		m.mu.Unlock()
+		return ErrNoOtherWorld
+	}
+	// Removed before the viewers are taken, so nobody joins the world after they were moved out
+	m.removeLocked(w)
+	m.mu.Unlock()
+
+	for _, v := range w.Viewers() {
+		if t, ok := v.(Traveler); ok && t.World() == w.Dimension {
+			t.ChangeDimension(fallback, pstn.BlockToEntity(fallback.Spawn))
+		}
+	}
+	err := w.save()
+	w.shutdown()
+	return err
+}
+
+// World returns the loaded world with the given name, or nil if there is none
+func (m *Manager) World(name string) *Dimension {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if w := m.find(name); w != nil {
+		return w.Dimension
+	}
+	return nil
+}
+
+// Worlds returns every loaded world, starting with the one players join
+func (m *Manager) Worlds() []*Dimension {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	worlds := make([]*Dimension, len(m.worlds))
+	for i, w := range m.worlds {
+		worlds[i] = w.Dimension
+	}
+	return worlds
+}
+
+// Default returns the world players join, which is the first world that was added and is still loaded
+func (m *Manager) Default() *Dimension {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.worlds) == 0 {
+		return nil
+	}
+	return m.worlds[0].Dimension
+}
+
+// Teleport moves t to the spawn of the world with the given name
+func (m *Manager) Teleport(t Traveler, name string) error {
+	to := m.World(name)
+	if to == nil {
+		return fmt.Errorf("%w: %s", ErrWorldNotFound, name)
+	}
+	pos := pstn.BlockToEntity(to.Spawn)
+	if t.World() == to {
+		t.Teleport(pos)
+	} else {
+		t.ChangeDimension(to, pos)
+	}
+	return nil
+}
+
+// Save saves the chunks and level.dat of every loaded world
+func (m *Manager) Save() error {
+	m.mu.Lock()
+	worlds := append([]*hostedWorld(nil), m.worlds...)
+	m.mu.Unlock()
+
+	var failed error
+	for _, w := range worlds {
+		if err := w.save(); err != nil {
+			log.Errorf("failed to save world %s: %v", w.Name, err)
+			failed = err
+		}
+	}
+	return failed
+}
+
+// Close saves and stops every world
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	worlds := m.worlds
+	m.worlds = nil
+	m.mu.Unlock()
+
+	var failed error
+	for _, w := range worlds {
+		if err := w.save(); err != nil {
+			log.Errorf("failed to save world %s: %v", w.Name, err)
+			failed = err
+		}
+		w.shutdown()
+	}
+	return failed
+}
+
+func (m *Manager) worldDir(name string) string {
+	return filepath.Join(m.dir, name)
+}
+
+func (m *Manager) checkNew(name string) error {
+	if !worldNames.MatchString(name) {
+		return fmt.Errorf("invalid world name %q", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.find(name) != nil {
+		return fmt.Errorf("%w: %s", ErrWorldExists, name)
+	}
+	return nil
+}
+
+// host creates the dimension of a world and starts ticking it
+func (m *Manager) host(settings WorldSettings, time int64) (*hostedWorld, error) {
+	generator, err := ParseGenerator(settings.Generator)
+	if err != nil {
+		return nil, err
+	}
+	w := &hostedWorld{
+		settings: settings,
+		time:     time,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	var store *anvil.Store
+	if m.dir != "" {
+		w.dir = m.worldDir(settings.Name)
+		store = anvil.Open(filepath.Join(w.dir, settings.Type.SaveDir()))
+	}
+	w.Dimension = New(Config{
+		Name:      settings.Name,
+		Type:      settings.Type,
+		Spawn:     settings.Spawn,
+		Seed:      settings.Seed,
+		Generator: generator,
+		Store:     store,
+		GameRules: settings.GameRules,
+	})
+	if w.Spawn == (pstn.Block{}) {
+		w.Spawn = surfaceSpawn(w.Dimension)
+	}
+
+	m.mu.Lock()
+	if m.find(settings.Name) != nil { // someone else was faster
+		m.mu.Unlock()
+		w.release()
+		return nil, fmt.Errorf("%w: %s", ErrWorldExists, settings.Name)
+	}
+	m.worlds = append(m.worlds, w)
+	m.mu.Unlock()
+
+	go func() {
+		defer close(w.stopped)
+		w.Run(w.stop)
+	}()
+	return w, nil
+}
+
+// surfaceSpawn returns the block above the highest block at x 0 z 0
+func surfaceSpawn(w *Dimension) pstn.Block {
+	y := w.Chunk(pstn.Chunk{}).HighestBlock(0, 0)
+	return pstn.Block{X: 0, Y: int32(y) + 1, Z: 0}
+}
+
+// remove stops players from finding the world through the manager
+func (m *Manager) remove(w *hostedWorld) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(w)
+}
+
+func (m *Manager) removeLocked(w *hostedWorld) {
+	for i, other := range m.worlds {
+		if other == w {
+			m.worlds = append(m.worlds[:i], m.worlds[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *Manager) find(name string) *hostedWorld {
+	for _, w := range m.worlds {
+		if w.Name == name {
+			return w
+		}
+	}
+	return nil
+}
+
+// save saves the chunks and level.dat, unless the world only lives in memory
+func (w *hostedWorld) save() error {
+	if w.dir == "" {
+		return nil
+	}
+	if err := w.Save(); err != nil {
+		return err
+	}
+	return w.saveLevel()
+}
+
+func (w *hostedWorld) saveLevel() error {
+	if w.dir == "" {
+		return nil
+	}
+	level := anvil.Level{
+		Name:      w.Name,
+		Spawn:     w.Spawn,
+		Seed:      w.Seed,
+		Time:      w.time + int64(w.TickStats().Tick),
+		GameType:  w.settings.GameType,
+		GameRules: w.GameRules(),
+		Dimension: w.Type.Name(),
+		Generator: w.settings.Generator,
+	}
+	if flat, ok := w.Generator.(*FlatGenerator); ok {
+		level.VanillaGenerator = flat.VanillaGenerator()
+	}
+	return anvil.WriteLevel(w.dir, level)
+}
+
+// shutdown stops the tick loop and releases the world
+func (w *hostedWorld) shutdown() {
+	close(w.stop)
+	<-w.stopped
+	w.release()
+}
+
+// release stops the workers and closes the store
+func (w *hostedWorld) release() {
+	w.Close()
+	if w.Store != nil {
+		if err := w.Store.Close(); err != nil {
+			log.Errorf("failed to close world %s: %v", w.Name, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package worlds
+
+import (
+	"errors"
+	"github.com/masp/mcgo/anvil"
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeTraveler is a player that moves between worlds as soon as it's told to
+type fakeTraveler struct {
+	world *Dimension
+	pos   pstn.Entity
+}
+
+func (f *fakeTraveler) ViewsChunk(pos pstn.Chunk) bool                 { return false }
+func (f *fakeTraveler) SendChunkChanges(changes *chunks.ColumnChanges) {}
+func (f *fakeTraveler) World() *Dimension                              { return f.world }
+func (f *fakeTraveler) Teleport(pos pstn.Entity)                       { f.pos = pos }
+
+func (f *fakeTraveler) ChangeDimension(to *Dimension, pos pstn.Entity) {
+	f.world.RemoveViewer(f)
+	f.world, f.pos = to, pos
+	to.AddViewer(f)
+}
+
+func TestManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "worlds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := NewManager(dir)
+	lobby, err := m.Create(WorldSettings{Name: "lobby", Spawn: pstn.Block{X: 0, Y: 64, Z: 0}})
+	if err != nil {
+		t.Fatalf("Create(lobby) error = %v", err)
+	}
+	arena, err := m.Create(WorldSettings{
+		Name:      "arena",
+		Type:      Nether,
+		Generator: "flat:minecraft:bedrock,2*minecraft:sand",
+		Spawn:     pstn.Block{X: 100, Y: 3, Z: -50},
+		GameRules: GameRules{"keepInventory": "true"},
+	})
+	if err != nil {
+		t.Fatalf("Create(arena) error = %v", err)
+	}
+	if _, err := m.Create(WorldSettings{Name: "arena"}); !errors.Is(err, ErrWorldExists) {
+		t.Errorf("creating a world twice: error = %v, want ErrWorldExists", err)
+	}
+	if _, err := m.Create(WorldSettings{Name: "../arena"}); err == nil {
+		t.Errorf("creating a world outside of the directory: error = nil")
+	}
+	if m.Default() != lobby {
+		t.Errorf("Default() isn't the first world")
+	}
+	if !arena.GameRules().Bool("keepInventory") || arena.GameRules().Int("randomTickSpeed") != 3 {
+		t.Errorf("arena has game rules %v, want the defaults with keepInventory", arena.GameRules())
+	}
+
+	player := &fakeTraveler{world: lobby}
+	lobby.AddViewer(player)
+	if err := m.Teleport(player, "arena"); err != nil {
+		t.Fatalf("Teleport() error = %v", err)
+	}
+	if player.world != arena || player.pos != pstn.BlockToEntity(arena.Spawn) {
+		t.Errorf("player is in %s at %v after teleporting, want the spawn of arena", player.world.Name, player.pos)
+	}
+
+	arena.Chunk(pstn.Chunk{X: 6, Z: -4}).SetBlockAt(0, 2, 0, blocks.Stone)
+	for arena.TickStats().Tick == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ticks := arena.TickStats().Tick
+	if err := m.Unload("arena"); err != nil {
+		t.Fatalf("Unload() error = %v", err)
+	}
+	if level, err := anvil.ReadLevel(filepath.Join(dir, "arena")); err != nil || level.Time < int64(ticks) {
+		t.Errorf("saved world age is %d, %v, want at least the %d ticks it ran", level.Time, err, ticks)
+	}
+	if m.World("arena") != nil {
+		t.Errorf("arena is still loaded after unloading it")
+	}
+	if player.world != lobby {
+		t.Errorf("player is in %s after unloading arena, want lobby", player.world.Name)
+	}
+
+	arena, err = m.Load("arena")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if arena.Type != Nether || arena.Spawn != (pstn.Block{X: 100, Y: 3, Z: -50}) || !arena.GameRules().Bool("keepInventory") {
+		t.Errorf("arena wasn't loaded with the settings it was created with")
+	}
+	chunk := arena.Chunk(pstn.Chunk{X: 6, Z: -4})
+	if got := chunk.BlockAt(0, 2, 0); got != blocks.Stone {
+		t.Errorf("changed block is %v after loading, want stone", got)
+	}
+	if got := chunk.BlockAt(1, 2, 0); got != blocks.Sand {
+		t.Errorf("generated block is %v after loading, want sand", got)
+	}
+
+	if _, err := m.Load("nowhere"); !errors.Is(err, ErrWorldNotFound) {
+		t.Errorf("Load() of a missing world: error = %v, want ErrWorldNotFound", err)
+	}
+	if err := m.Unload("arena"); err != nil {
+		t.Fatalf("Unload() error = %v", err)
+	}
+	if err := m.Unload("lobby"); !errors.Is(err, ErrNoOtherWorld) {
+		t.Errorf("unloading the last world with a player in it: error = %v, want ErrNoOtherWorld", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
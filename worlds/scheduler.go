@@ -0,0 +1,165 @@
+package worlds
+
+import (
+	"container/heap"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Task is a handle to a scheduled task
+type Task struct {
+	fn        func()
+	due       uint64 // tick the task runs on next
+	period    uint64 // ticks between runs for repeating tasks, 0 if it only runs once
+	seq       uint64 // tasks due on the same tick run in the order they were scheduled
+	cancelled int32
+}
+
+// Cancel stops the task from running again. A task can cancel itself while it's running.
+func (t *Task) Cancel() {
+	atomic.StoreInt32(&t.cancelled, 1)
+}
+
+func (t *Task) Cancelled() bool {
+	return atomic.LoadInt32(&t.cancelled) == 1
+}
+
+// Scheduler runs tasks on the tick goroutine of a dimension, where they can change the world without racing players or
+// other tasks. Tasks can be scheduled from any goroutine. Delays and periods are in ticks, see Ticks.
+type Scheduler struct {
+	mu    sync.Mutex
+	tick  uint64 // the tick that is running or ran last
+	seq   uint64
+	tasks taskQueue
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Ticks converts a duration to the number of ticks that take as long at full speed, rounded down
+func Ticks(d time.Duration) int {
+	return int(d / TickDuration)
+}
+
+// Later runs f once on the tick goroutine after delay ticks. With a delay of 0 it runs on the next tick.
+func (s *Scheduler) Later(delay int, f func()) *Task {
+	return s.schedule(delay, 0, f)
+}
+
+// Every runs f on the tick goroutine after delay ticks and then every period ticks until it's cancelled
+func (s *Scheduler) Every(delay int, period int, f func()) *Task {
+	if period < 1 {
+		panic(fmt.Errorf("scheduler: invalid period of %d ticks", period))
+	}
+	return s.schedule(delay, period, f)
+}
+
+// Async runs work on its own goroutine, for anything too slow to do during a tick like file or network access.
+// The function work returns is then run on the tick goroutine to apply the result to the world, unless the task was
+// cancelled in the meantime. work may return nil if there is nothing to apply.
+func (s *Scheduler) Async(work func() (sync func())) *Task {
+	t := &Task{}
+	go func() {
+		defer logPanic("scheduled task")
+		apply := work()
+		if apply == nil || t.Cancelled() {
+			return
+		}
+		t.fn = apply
+		s.add(t, 0)
+	}()
+	return t
+}
+
+func (s *Scheduler) schedule(delay int, period int, f func()) *Task {
+	if delay < 0 {
+		panic(fmt.Errorf("scheduler: invalid delay of %d ticks", delay))
+	}
+	t := &Task{fn: f, period: uint64(period)}
+	s.add(t, delay)
+	return t
+}
+
+func (s *Scheduler) add(t *Task, delay int) {
+	if delay < 1 {
+		delay = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.due = s.tick + uint64(delay)
+	s.seq++
+	t.seq = s.seq
+	heap.Push(&s.tasks, t)
+}
+
+// run starts the next tick and runs every task that is due on it. It must only be called on the tick goroutine.
+func (s *Scheduler) run() {
+	s.mu.Lock()
+	s.tick++
+	tick := s.tick
+	var due []*Task
+	for len(s.tasks) > 0 && s.tasks[0].due <= tick {
+		due = append(due, heap.Pop(&s.tasks).(*Task))
+	}
+	s.mu.Unlock()
+
+	for _, t := range due {
+		if t.Cancelled() {
+			continue
+		}
+		runTask(t)
+		if t.period > 0 && !t.Cancelled() {
+			s.mu.Lock()
+			t.due = tick + t.period
+			heap.Push(&s.tasks, t)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Pending returns the number of tasks waiting to run, not counting async tasks that are still working
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tasks)
+}
+
+func runTask(t *Task) {
+	defer logPanic("scheduled task")
+	t.fn()
+}
+
+// logPanic keeps a broken task, input or entity from taking the whole dimension down with it. It must be deferred.
+func logPanic(what string) {
+	if err := recover(); err != nil {
+		log.Errorf("%s panicked: %v", what, err)
+	}
+}
+
+// taskQueue is a heap of tasks, the first task to run first
+type taskQueue []*Task
+
+func (q taskQueue) Len() int { return len(q) }
+
+func (q taskQueue) Less(i, j int) bool {
+	if q[i].due != q[j].due {
+		return q[i].due < q[j].due
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q taskQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *taskQueue) Push(x interface{}) { *q = append(*q, x.(*Task)) }
+
+func (q *taskQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,98 @@
+package worlds
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Later(t *testing.T) {
+	s := NewScheduler()
+	var ran []string
+	s.Later(2, func() { ran = append(ran, "a") })
+	s.Later(0, func() { ran = append(ran, "b") })
+	s.Later(1, func() { ran = append(ran, "c") })
+	s.Later(1, func() { panic("broken task") })
+	s.Later(1, func() {
+		ran = append(ran, "d")
+		s.Later(0, func() { ran = append(ran, "e") })
+	})
+	cancelled := s.Later(1, func() { ran = append(ran, "cancelled") })
+	cancelled.Cancel()
+
+	s.run()
+	if want := []string{"b", "c", "d"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("first tick ran %v, want %v", ran, want)
+	}
+	ran = nil
+	s.run()
+	if want := []string{"a", "e"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("second tick ran %v, want %v", ran, want)
+	}
+	if s.Pending() != 0 {
+		t.Errorf("Pending() = %d after every task ran, want 0", s.Pending())
+	}
+}
+
+func TestScheduler_Every(t *testing.T) {
+	s := NewScheduler()
+	var ranOn []int
+	tick := 0
+	var task *Task
+	task = s.Every(1, 3, func() {
+		ranOn = append(ranOn, tick)
+		if len(ranOn) == 3 {
+			task.Cancel()
+		}
+	})
+	for tick = 1; tick <= 12; tick++ {
+		s.run()
+	}
+	if want := []int{1, 4, 7}; !reflect.DeepEqual(ranOn, want) {
+		t.Errorf("repeating task ran on ticks %v, want %v", ranOn, want)
+	}
+	if s.Pending() != 0 {
+		t.Errorf("Pending() = %d after cancelling, want 0", s.Pending())
+	}
+}
+
+// waitForPending runs the scheduler until n tasks are waiting, which happens once async tasks finished their work
+func waitForPending(t *testing.T, s *Scheduler, n int) {
+	deadline := time.Now().Add(time.Second)
+	for s.Pending() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("async task didn't finish its work")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduler_Async(t *testing.T) {
+	s := NewScheduler()
+	applied := ""
+	s.Async(func() func() {
+		result := "loaded"
+		return func() { applied = result }
+	})
+	waitForPending(t, s, 1)
+	if applied != "" {
+		t.Fatalf("result was applied before the tick")
+	}
+	s.run()
+	if applied != "loaded" {
+		t.Errorf("applied = %q after the tick, want %q", applied, "loaded")
+	}
+
+	release := make(chan struct{})
+	task := s.Async(func() func() {
+		<-release
+		return func() { applied = "cancelled" }
+	})
+	task.Cancel()
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	s.run()
+	if applied != "loaded" || s.Pending() != 0 {
+		t.Errorf("result of a cancelled async task was applied")
+	}
+}
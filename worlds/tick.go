@@ -0,0 +1,165 @@
+package worlds
+
+import (
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	TicksPerSecond = 20
+	TickDuration   = time.Second / TicksPerSecond
+
+	// maxTickLag is how far behind the loop can fall before it gives up on catching up and skips the missed ticks
+	maxTickLag = 2 * time.Second
+	// tickStatsWindow is the number of ticks the average tick time is taken over
+	tickStatsWindow = 100
+)
+
+// Entity is anything in a dimension that is updated every tick. Tick is always called on the tick goroutine.
+type Entity interface {
+	Tick()
+}
+
+// TickStats describe how long the recent ticks took
+type TickStats struct {
+	Tick    uint64        // number of ticks run so far
+	Last    time.Duration // how long the last tick took
+	Average time.Duration // average over the last ticks
+	TPS     float64       // ticks per second, which is lower than TicksPerSecond if ticks take too long
+}
+
+// clock is where the tick loop gets the time from, so tests can run it without waiting
+type clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// tickState is everything the tick loop keeps in the dimension
+type tickState struct {
+	clock clock
+
+	inputsMu sync.Mutex
+	inputs   []func()
+
+	entities map[Entity]struct{} // only used on the tick goroutine
+
+	statsMu   sync.Mutex
+	stats     TickStats
+	durations [tickStatsWindow]time.Duration
+}
+
+// Submit queues f to run on the tick goroutine at the start of the next tick. Everything that changes the world in
+// response to a player, like breaking a block, should go through here so it happens in a well defined order.
+func (w *Dimension) Submit(f func()) {
+	w.ticks.inputsMu.Lock()
+	defer w.ticks.inputsMu.Unlock()
+	w.ticks.inputs = append(w.ticks.inputs, f)
+}
+
+// AddEntity starts ticking e from the next tick on
+func (w *Dimension) AddEntity(e Entity) {
+	w.Submit(func() {
+		w.ticks.entities[e] = struct{}{}
+	})
+}
+
+func (w *Dimension) RemoveEntity(e Entity) {
+	w.Submit(func() {
+		delete(w.ticks.entities, e)
+	})
+}
+
+// TickStats returns how long the recent ticks took
+func (w *Dimension) TickStats() TickStats {
+	w.ticks.statsMu.Lock()
+	defer w.ticks.statsMu.Unlock()
+	return w.ticks.stats
+}
+
+// Run ticks the dimension TicksPerSecond times a second until stop is closed. If a tick takes too long, the next
+// ones are run right away to catch up, unless the loop fell more than a couple of seconds behind, in which case the
+// missed ticks are skipped.
+func (w *Dimension) Run(stop <-chan struct{}) {
+	c := w.ticks.clock
+	next := c.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		start := c.Now()
+		w.tick()
+		w.recordTick(c.Now().Sub(start))
+
+		next = next.Add(TickDuration)
+		now := c.Now()
+		if lag := now.Sub(next); lag > maxTickLag {
+			log.Warnf("Can't keep up! Running %v behind, skipping %d ticks", lag, lag/TickDuration)
+			next = now
+		} else if lag < 0 {
+			c.Sleep(-lag)
+		}
+	}
+}
+
+// tick runs a single tick: the queued inputs first, then the scheduled tasks and the entities, and finally the
+// changes are sent to players
+func (w *Dimension) tick() {
+	w.ticks.inputsMu.Lock()
+	inputs := w.ticks.inputs
+	w.ticks.inputs = nil
+	w.ticks.inputsMu.Unlock()
+	for _, input := range inputs {
+		runInput(input)
+	}
+
+	w.Scheduler.run()
+
+	for e := range w.ticks.entities {
+		tickEntity(e)
+	}
+
+	w.FlushChanges()
+}
+
+func runInput(f func()) {
+	defer logPanic("input")
+	f()
+}
+
+func tickEntity(e Entity) {
+	defer logPanic("entity")
+	e.Tick()
+}
+
+func (w *Dimension) recordTick(took time.Duration) {
+	w.ticks.statsMu.Lock()
+	defer w.ticks.statsMu.Unlock()
+
+	s := &w.ticks.stats
+	w.ticks.durations[s.Tick%tickStatsWindow] = took
+	s.Tick++
+	s.Last = took
+
+	n := s.Tick
+	if n > tickStatsWindow {
+		n = tickStatsWindow
+	}
+	var total time.Duration
+	for _, d := range w.ticks.durations[:n] {
+		total += d
+	}
+	s.Average = total / time.Duration(n)
+	s.TPS = TicksPerSecond
+	if s.Average > TickDuration {
+		s.TPS = float64(time.Second) / float64(s.Average)
+	}
+}
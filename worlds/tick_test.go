@@ -0,0 +1,112 @@
+package worlds
+
+import (
+	"github.com/masp/mcgo/blocks"
+	"github.com/masp/mcgo/chunks"
+	"github.com/masp/mcgo/pstn"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+// funcEntity calls its function every tick
+type funcEntity struct {
+	f func()
+}
+
+func (e *funcEntity) Tick() { e.f() }
+
+// recordingViewer sees every chunk and records the changes it's sent
+type recordingViewer struct {
+	events *[]string
+}
+
+func (r recordingViewer) ViewsChunk(pstn.Chunk) bool { return true }
+
+func (r recordingViewer) SendChunkChanges(*chunks.ColumnChanges) {
+	*r.events = append(*r.events, "changes")
+}
+
+func TestDimension_tick(t *testing.T) {
+	w := New(Config{Generator: &countingGenerator{}})
+	defer w.Close()
+	w.Chunk(pstn.Chunk{})
+
+	var events []string
+	w.AddViewer(recordingViewer{&events})
+	w.AddEntity(&funcEntity{func() {
+		events = append(events, "entity")
+	}})
+	w.Submit(func() {
+		events = append(events, "input")
+		w.SetBlockAt(pstn.Block{X: 1, Y: 1, Z: 1}, blocks.Glass)
+	})
+
+	w.tick()
+	if want := []string{"input", "entity", "changes"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("first tick ran %v, want %v", events, want)
+	}
+
+	events = nil
+	w.tick()
+	if want := []string{"entity"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("second tick ran %v, want %v", events, want)
+	}
+
+	events = nil
+	w.Submit(func() { panic("broken input") })
+	w.AddEntity(&funcEntity{func() { panic("broken entity") }})
+	w.tick()
+	if want := []string{"entity"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("tick with panics ran %v, want %v", events, want)
+	}
+}
+
+func TestDimension_Run(t *testing.T) {
+	w := New(Config{Generator: &countingGenerator{}})
+	defer w.Close()
+	c := &fakeClock{now: time.Unix(0, 0)}
+	w.ticks.clock = c
+
+	// How long every tick takes: the second one is slow enough that the next one has to catch up right away, and
+	// the sixth one is so slow that the missed ticks are skipped
+	durations := []time.Duration{
+		10 * time.Millisecond, 120 * time.Millisecond, 0, 0, 0, 3 * time.Second, 0,
+	}
+	stop := make(chan struct{})
+	ticks := 0
+	w.AddEntity(&funcEntity{func() {
+		c.now = c.now.Add(durations[ticks])
+		ticks++
+		if ticks == len(durations) {
+			close(stop)
+		}
+	}})
+	w.Run(stop)
+
+	want := []time.Duration{40 * time.Millisecond, 30 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
+	if !reflect.DeepEqual(c.sleeps, want) {
+		t.Errorf("slept %v between ticks, want %v", c.sleeps, want)
+	}
+
+	stats := w.TickStats()
+	if stats.Tick != uint64(len(durations)) || stats.Last != 0 {
+		t.Errorf("TickStats() = %+v, want %d ticks with the last one taking 0s", stats, len(durations))
+	}
+	if stats.Average != 3130*time.Millisecond/7 || stats.TPS >= TicksPerSecond {
+		t.Errorf("TickStats() = %+v, want an average of %v and less than %d TPS",
+			stats, 3130*time.Millisecond/7, TicksPerSecond)
+	}
+}